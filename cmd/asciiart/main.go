@@ -2,9 +2,14 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"image"
+	"image/color"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/nebbyJammin/asciiart/pkg/asciiart"
 )
@@ -27,6 +32,53 @@ const (
 	widthUsage			= "Specifies the target width. May be ignored depending on the downsampling mode."
 	heightUsage			= "Specifies the target height. May be ignored depending on the downsampling mode."
 	richUsage			= "Alias for -c -s -b -cspace=24bit"
+	brightnessUsage		= "Shifts the brightness of the image, as a fraction of full brightness between -1 and 1 (e.g. 0.2 brightens by 20%)."
+	contrastUsage		= "Adjusts the contrast of the image around mid grey, between -1 and 1. Positive values increase contrast, negative values decrease it."
+	gammaUsage			= "Applies gamma correction to the image. Values > 1 brighten dark images, values < 1 darken bright images."
+	linearUsage			= "Enables linear light (gamma correct) downscaling and luminosity. Slower, but gradients and anti-aliased edges keep their perceived brightness."
+	lumModelUsage		= "Specifies how the r, g, b channels are combined into luminosity:\n" +
+							`  - "rec709" | "709"` + "\n" +
+							`  - "rec601" | "601"` + "\n" +
+							`  - "rec2020" | "2020"` + "\n" +
+							`  - "average" | "avg"` + "\n" +
+							`  - "max"` + "\n" +
+							`  - "red" | "green" | "blue"` + "\n"
+	alphaUsage			= "Specifies how transparent pixels are handled:\n" +
+							`  - "multiply": Transparent pixels are rendered like black pixels` + "\n" +
+							`  - "composite": Transparent pixels are composited onto -alpha-bg` + "\n" +
+							`  - "transparent": Characters below -alpha-threshold are left blank` + "\n"
+	alphaBgUsage		= "Specifies the background colour (hex, e.g. #ffffff) transparent pixels are composited onto. Only used with -alpha=composite."
+	alphaThresholdUsage	= "Specifies the minimum alpha (0-255) for a character to be drawn. Only used with -alpha=transparent."
+	bgUsage				= "Specifies the background of the terminal, which inverts the character ramp and colour clamps on light backgrounds:\n" +
+							`  - "auto": Detected from the COLORFGBG environment variable, falling back to dark` + "\n" +
+							`  - "dark"` + "\n" +
+							`  - "light"` + "\n"
+	formatUsage			= "Specifies the output format:\n" +
+							`  - "ansi": Text with ANSI escape sequences for the terminal` + "\n" +
+							`  - "text": Plain text without any escape sequences` + "\n" +
+							`  - "html": A <pre> block with coloured <span>s` + "\n" +
+							`  - "svg": An SVG image` + "\n" +
+							`  - "json": The cell grid as JSON, for rendering by other programs` + "\n" +
+							`  - "binary": The cell grid in a compact binary format (use with -o, one input only)` + "\n" +
+							`  - "asciicast": An asciinema recording (asciicast v2) of every frame of an animated GIF` + "\n" +
+							`  - "png": A PNG image drawn with a built in bitmap font (use with -o, one input only)` + "\n" +
+							`  - "gif": A GIF image drawn with a built in bitmap font (use with -o, one input only)` + "\n"
+	trimUsage			= "Removes trailing spaces from every line of -format=text output."
+	parallelUsage		= "Specifies how many goroutines each stage of the conversion is split over. 0 uses every CPU."
+	lineResetUsage		= "Resets all styles at the end of every line of -format=ansi output, so lines can be copied or viewed (e.g. with less -R) on their own."
+	outputUsage			= "Specifies the file to write the output to, instead of stdout."
+	htmlClassesUsage	= "Styles -format=html output with classes and a <style> block instead of inline styles."
+	svgCellWidthUsage	= "Specifies the width of each character of -format=svg output."
+	svgCellHeightUsage	= "Specifies the height of each character of -format=svg output."
+	svgFontUsage		= "Specifies the font family of -format=svg output."
+	svgBgUsage			= "Specifies the background colour (hex, e.g. #000000) of -format=svg output. Transparent if not specified."
+	svgCellBgUsage		= "Draws the colour of the image behind every character of -format=svg output. Best used with -cspace none."
+	loopUsage			= "Specifies how many times play plays the animation. 0 plays forever. By default uses the loop count of the GIF."
+	speedUsage			= "Specifies the playback speed of play (e.g. 2 plays twice as fast)."
+	equalizeUsage		= "Specifies which histogram equalization to apply to the luminosity:\n" +
+							`  - "none"` + "\n" +
+							`  - "global"` + "\n" +
+							`  - "clahe"` + "\n"
 )
 
 func main() {
@@ -37,6 +89,29 @@ func main() {
 	colorSpace := "4bit"
 	width := 100
 	height := 100
+	brightness := float64(0)
+	contrast := float64(0)
+	gamma := float64(1)
+	equalizeStr := "none"
+	useLinearLight := false
+	lumModelStr := "rec709"
+	alphaPolicyStr := "multiply"
+	alphaBgStr := "#000000"
+	alphaThreshold := 128
+	bgStr := "auto"
+	formatStr := "ansi"
+	useHTMLClasses := false
+	svgCellWidth := float64(8)
+	svgCellHeight := float64(16)
+	svgFont := "monospace"
+	svgBgStr := ""
+	svgCellBg := false
+	outputPath := ""
+	trimTrailingSpace := false
+	resetAtLineEnd := false
+	parallelism := 1
+	loops := -1
+	speed := float64(1)
 
 	enableSobel := func(s string) error {
 		useSobel = true
@@ -74,6 +149,31 @@ func main() {
 	flag.IntVar(&height, "h", 100, heightUsage)
 	flag.IntVar(&width, "height", 100, "alias for -h")
 
+	flag.Float64Var(&brightness, "brightness", 0, brightnessUsage)
+	flag.Float64Var(&contrast, "contrast", 0, contrastUsage)
+	flag.Float64Var(&gamma, "gamma", 1, gammaUsage)
+	flag.StringVar(&equalizeStr, "equalize", "none", equalizeUsage)
+	flag.BoolVar(&useLinearLight, "linear", false, linearUsage)
+	flag.StringVar(&lumModelStr, "lum-model", "rec709", lumModelUsage)
+
+	flag.StringVar(&alphaPolicyStr, "alpha", "multiply", alphaUsage)
+	flag.StringVar(&alphaBgStr, "alpha-bg", "#000000", alphaBgUsage)
+	flag.IntVar(&alphaThreshold, "alpha-threshold", 128, alphaThresholdUsage)
+
+	flag.StringVar(&bgStr, "bg", "auto", bgUsage)
+
+	flag.StringVar(&formatStr, "format", "ansi", formatUsage)
+	flag.StringVar(&outputPath, "o", "", outputUsage)
+	flag.BoolVar(&trimTrailingSpace, "trim", false, trimUsage)
+	flag.BoolVar(&resetAtLineEnd, "line-reset", false, lineResetUsage)
+	flag.IntVar(&parallelism, "parallel", 1, parallelUsage)
+	flag.BoolVar(&useHTMLClasses, "html-classes", false, htmlClassesUsage)
+	flag.Float64Var(&svgCellWidth, "svg-cell-width", 8, svgCellWidthUsage)
+	flag.Float64Var(&svgCellHeight, "svg-cell-height", 16, svgCellHeightUsage)
+	flag.StringVar(&svgFont, "svg-font", "monospace", svgFontUsage)
+	flag.StringVar(&svgBgStr, "svg-bg", "", svgBgUsage)
+	flag.BoolVar(&svgCellBg, "svg-cell-bg", false, svgCellBgUsage)
+
 	flag.StringVar(&downscalingModeStr, "downscale-mode", "respect-aspect-ratio", downscalingUsage)
 
 	flag.StringVar(&colorSpace, "cspace", "none", colorSpaceUsage)
@@ -82,8 +182,17 @@ func main() {
 	flag.BoolFunc("rich", richUsage, enableRich)
 	flag.BoolFunc("r", richUsage, enableRich)
 
+	// `asciiart play [flags] file` plays an animation in the terminal instead of converting
+	playMode := len(os.Args) > 1 && os.Args[1] == "play"
+	flagArgs := os.Args[1:]
+	if playMode {
+		flag.IntVar(&loops, "loop", -1, loopUsage)
+		flag.Float64Var(&speed, "speed", 1, speedUsage)
+		flagArgs = os.Args[2:]
+	}
+
 	// Parse flags
-	flag.Parse()
+	flag.CommandLine.Parse(flagArgs)
 
 	// Interpret downscaling mode string as enum value
 	var dMode asciiart.DownscalingMode
@@ -117,54 +226,269 @@ func main() {
 		panic(msg)
 	}
 
+	var lumModel asciiart.LuminanceModel
+
+	switch lumModelStr {
+	case "rec709", "709":
+		lumModel = asciiart.LuminanceModels.Rec709()
+	case "rec601", "601":
+		lumModel = asciiart.LuminanceModels.Rec601()
+	case "rec2020", "2020":
+		lumModel = asciiart.LuminanceModels.Rec2020()
+	case "average", "avg":
+		lumModel = asciiart.LuminanceModels.Average()
+	case "max":
+		lumModel = asciiart.LuminanceModels.MaxChannel()
+	case "red":
+		lumModel = asciiart.LuminanceModels.Red()
+	case "green":
+		lumModel = asciiart.LuminanceModels.Green()
+	case "blue":
+		lumModel = asciiart.LuminanceModels.Blue()
+	default:
+		msg := fmt.Sprintf("Got unknown luminance model: %s", lumModelStr)
+		panic(msg)
+	}
+
+	var alphaPolicy asciiart.AlphaPolicy
+
+	switch alphaPolicyStr {
+	case "multiply":
+		alphaPolicy = asciiart.AlphaPolicies.Multiply()
+	case "composite":
+		alphaPolicy = asciiart.AlphaPolicies.Composite()
+	case "transparent":
+		alphaPolicy = asciiart.AlphaPolicies.Transparent()
+	default:
+		msg := fmt.Sprintf("Got unknown alpha policy: %s", alphaPolicyStr)
+		panic(msg)
+	}
+
+	alphaBg, err := parseHexColor(alphaBgStr)
+	if err != nil {
+		panic(err)
+	}
+
+	var terminalBg asciiart.TerminalBackground
+
+	switch bgStr {
+	case "auto":
+		// Fall back to dark (the first return value) if COLORFGBG is not set or not understood
+		terminalBg, _ = asciiart.ParseCOLORFGBG(os.Getenv("COLORFGBG"))
+	case "dark":
+		terminalBg = asciiart.TerminalBackgrounds.Dark()
+	case "light":
+		terminalBg = asciiart.TerminalBackgrounds.Light()
+	default:
+		msg := fmt.Sprintf("Got unknown terminal background: %s", bgStr)
+		panic(msg)
+	}
+
+	var renderer asciiart.Renderer
+
+	switch formatStr {
+	case "ansi":
+		// Left to the converter, which streams ANSI output row by row
+		renderer = nil
+	case "text":
+		renderer = asciiart.TextRenderer(asciiart.TextRendererOptions{
+			TrimTrailingSpace: trimTrailingSpace,
+		})
+	case "html":
+		renderer = asciiart.HTMLRenderer(asciiart.HTMLRendererOptions{
+			UseClasses: useHTMLClasses,
+			Stylesheet: useHTMLClasses,
+		})
+	case "svg":
+		svgOpts := asciiart.SVGRendererOptions{
+			CellWidth: svgCellWidth,
+			CellHeight: svgCellHeight,
+			FontFamily: svgFont,
+			DrawCellBackgrounds: svgCellBg,
+		}
+
+		if svgBgStr != "" {
+			svgBg, err := parseHexColor(svgBgStr)
+			if err != nil {
+				panic(err)
+			}
+			svgOpts.Background = svgBg
+		}
+
+		renderer = asciiart.SVGRenderer(svgOpts)
+	case "asciicast":
+		// Written by convertAsciicast() instead of the renderer
+		renderer = asciiart.ANSIRenderer()
+	case "json":
+		renderer = asciiart.JSONRenderer()
+	case "binary":
+		renderer = asciiart.BinaryRenderer()
+	case "png":
+		renderer = asciiart.PNGRenderer(asciiart.ImageRendererOptions{})
+	case "gif":
+		renderer = asciiart.GIFRenderer(asciiart.ImageRendererOptions{})
+	default:
+		msg := fmt.Sprintf("Got unknown output format: %s", formatStr)
+		panic(msg)
+	}
+
+	var lumFilters []asciiart.LuminosityFilter
+
+	switch equalizeStr {
+	case "none":
+	case "global":
+		lumFilters = append(lumFilters, asciiart.HistogramEqualizationFilter())
+	case "clahe":
+		lumFilters = append(lumFilters, asciiart.CLAHEFilter(8, 4, 3))
+	default:
+		msg := fmt.Sprintf("Got unknown equalization: %s", equalizeStr)
+		panic(msg)
+	}
+
 	asciiconv := asciiart.New(
 		asciiart.WithSobelMagSquaredThresholdNormalized(80000),
-		asciiart.WithSobelLaplacianThresholdNormalized(300),
+		asciiart.WithSobelLaplacianThresholdNormalized(128),
 		asciiart.WithBoldedSobelOutline(useBoldOutline),
 		asciiart.WithOutputAspectRatio(aspectRatio),
 		asciiart.WithDownscalingMode(dMode),
 		asciiart.WithSobel(useSobel),
+		asciiart.WithBrightness(brightness),
+		asciiart.WithContrast(contrast),
+		asciiart.WithGamma(gamma),
+		asciiart.WithLuminosityFilters(lumFilters...),
+		asciiart.WithLinearLight(useLinearLight),
+		asciiart.WithLuminanceModel(lumModel),
+		asciiart.WithAlphaPolicy(alphaPolicy),
+		asciiart.WithAlphaBackground(alphaBg),
+		asciiart.WithAlphaThreshold(alphaThreshold),
+		asciiart.WithTerminalBackground(terminalBg),
+		asciiart.WithRenderer(renderer),
+		asciiart.WithANSIRendererOptions(asciiart.ANSIRendererOptions{
+			ResetAtLineEnd: resetAtLineEnd,
+		}),
+		asciiart.WithCellBackgrounds(svgCellBg && formatStr == "svg"),
+		asciiart.WithParallelism(parallelism),
 		asciiart.WithDefaultLumosityMapper(),
 		asciiart.WithDefaultEdgeMapperFactory(),
 		colorMapperOpt,
 	)
 
-	args := flag.Args()
-	if len(args) == 0 {
-		scanner := bufio.NewScanner(os.Stdin)
-		for scanner.Scan() {
-			filename := scanner.Text()
-			res, err := convertAscii(asciiconv, filename, width, height)
+	if playMode {
+		player := newPlayer(speed)
+		defer player.close()
+
+		for _, arg := range flag.Args() {
+			err := player.play(asciiconv, arg, width, height, loops)
+			if errors.Is(err, errPlaybackQuit) {
+				break
+			}
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "%s\n", err)
-				continue
 			}
+		}
+		return
+	}
+
+	// Images and binary canvases cannot be concatenated, so only one input can be converted to them
+	singleInput := formatStr == "png" || formatStr == "gif" || formatStr == "binary"
+
+	args := flag.Args()
+	if singleInput && len(args) > 1 {
+		msg := fmt.Sprintf("Only one input can be converted with -format %s, got %d", formatStr, len(args))
+		panic(msg)
+	}
+
+	var out io.Writer = os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
 
-			fmt.Println(res)
+		out = f
+	}
+
+	convert := convertAscii
+	if formatStr == "asciicast" {
+		convert = convertAsciicast
+	}
+
+	// Binary output and recordings are written as is, text output is separated by new lines
+	writeAsIs := formatStr == "png" || formatStr == "gif" || formatStr == "binary" || formatStr == "asciicast"
+	converted := 0
+	convertFile := func(path string) {
+		if singleInput && converted > 0 {
+			fmt.Fprintf(os.Stderr, "Only one input can be converted with -format %s, skipping %s\n", formatStr, path)
+			return
+		}
+
+		if err := convert(asciiconv, path, out, width, height); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return
+		}
+		converted++
+
+		if !writeAsIs {
+			fmt.Fprintln(out)
+		}
+	}
+
+	if len(args) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			convertFile(scanner.Text())
 		}
 	} else {
 		for _, arg := range args {
-			res, err := convertAscii(asciiconv, arg, width, height)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "%s\n", err)
-				continue
-			}
-
-			fmt.Println(res)
+			convertFile(arg)
 		}
 	}
 }
 
-func convertAscii(asciiconv *asciiart.AsciiConverter, path string, width, height int) (string, error) {
-	f, err := os.ReadFile(path)
+// parseHexColor parses a colour in the form #rrggbb (the # is optional)
+func parseHexColor(s string) (color.RGBA, error) {
+	var c color.RGBA
+	hex := strings.TrimPrefix(s, "#")
+
+	if _, err := fmt.Sscanf(hex, "%02x%02x%02x", &c.R, &c.G, &c.B); err != nil || len(hex) != 6 {
+		return c, fmt.Errorf("Invalid hex colour %q, expected #rrggbb", s)
+	}
+
+	c.A = 255
+	return c, nil
+}
+
+func convertAscii(asciiconv *asciiart.AsciiConverter, path string, w io.Writer, width, height int) error {
+	f, err := os.Open(path)
 	if err != nil {
-		return "", fmt.Errorf("Error reading file %s: %w", f, err)
+		return fmt.Errorf("Error reading file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("Error converting ascii: %s", err)
+	}
+
+	// Write straight to the output as it is rendered
+	if err := asciiconv.ConvertTo(w, img, width, height); err != nil {
+		return fmt.Errorf("Error writing output: %s", err)
 	}
 
-	res, err := asciiconv.ConvertBytes(f, width, height)
+	return nil
+}
+
+func convertAsciicast(asciiconv *asciiart.AsciiConverter, path string, w io.Writer, width, height int) error {
+	f, err := os.Open(path)
 	if err != nil {
-		return "", fmt.Errorf("Error converting ascii: %s", err)
+		return fmt.Errorf("Error reading file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := asciiconv.ConvertAsciicast(f, w, width, height); err != nil {
+		return fmt.Errorf("Error converting ascii: %s", err)
 	}
 
-	return res, nil
+	return nil
 }
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nebbyJammin/asciiart/pkg/asciiart"
+)
+
+const (
+	// speedStep is how much the + and - keys change the playback speed by
+	speedStep	= 1.25
+	minSpeed	= 1.0 / 16
+	maxSpeed	= 16.0
+)
+
+// errPlaybackQuit is returned by player.play() when the user quits, so the remaining files are not played
+var errPlaybackQuit = errors.New("Playback quit")
+
+/*
+player plays animations in place in the terminal. A single player is used for the whole play command, so key presses are read by one goroutine no matter how many files are played, and speed changes carry over to the next file.
+*/
+type player struct {
+	out			*bufio.Writer
+	keys		chan byte
+	interrupt	chan os.Signal
+	restore		func()
+	speed		float64
+}
+
+/*
+newPlayer switches the terminal to single key input (if it can) and starts reading key presses. close() must be called once playback is over, to restore the terminal.
+*/
+func newPlayer(speed float64) *player {
+	p := &player {
+		out: bufio.NewWriter(os.Stdout),
+		keys: make(chan byte),
+		interrupt: make(chan os.Signal, 1),
+		speed: min(maxSpeed, max(minSpeed, speed)),
+	}
+
+	signal.Notify(p.interrupt, os.Interrupt, syscall.SIGTERM)
+
+	var hasKeyInput bool
+	p.restore, hasKeyInput = enableKeyInput()
+	if hasKeyInput {
+		go readKeys(p.keys)
+	}
+
+	return p
+}
+
+// close restores the terminal
+func (p *player) close() {
+	signal.Stop(p.interrupt)
+	p.restore()
+}
+
+/*
+play plays every frame of the animation at path in place in the terminal, until the animation has been played loops times (0 plays forever, -1 uses the loop count of the GIF). It returns errPlaybackQuit if the user quits or playback is interrupted.
+
+While playing:
+	- space pauses/resumes
+	- + and - change the speed
+	- q quits
+*/
+func (p *player) play(asciiconv *asciiart.AsciiConverter, path string, width, height, loops int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Error reading file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	anim, err := asciiconv.ConvertAnimation(f, width, height)
+	if err != nil {
+		return fmt.Errorf("Error converting ascii: %s", err)
+	}
+
+	if len(anim.Frames) == 0 {
+		return nil
+	}
+
+	if loops < 0 {
+		loops = playCount(anim.LoopCount)
+	}
+
+	// Reset the styles and show the cursor again however playback ends
+	defer func() {
+		p.out.WriteString("\x1b[0m\x1b[?25h")
+		p.out.Flush()
+	}()
+
+	// Hide the cursor. Only the cells that change between frames are redrawn
+	p.out.WriteString("\x1b[?25l")
+	frameEnc := asciiart.NewFrameEncoder(p.out)
+	paused := false
+
+	for played := 0; loops == 0 || played < loops; played++ {
+		for _, frame := range anim.Frames {
+			if err := frameEnc.Encode(frame.Canvas); err != nil {
+				return err
+			}
+			if err := p.out.Flush(); err != nil {
+				return err
+			}
+
+			delay := frameDelay{remaining: frame.Delay, resumed: time.Now()}
+			timer := time.NewTimer(delay.wait(p.speed))
+			if paused {
+				timer.Stop()
+			}
+
+		wait:
+			for {
+				select {
+					case <-p.interrupt:
+						timer.Stop()
+						return errPlaybackQuit
+					case key := <-p.keys:
+						now := time.Now()
+
+						switch key {
+							case 'q', 'Q':
+								timer.Stop()
+								return errPlaybackQuit
+							case ' ':
+								paused = !paused
+								if paused {
+									delay.pause(now, p.speed)
+									timer.Stop()
+								} else {
+									delay.resume(now)
+									timer.Reset(delay.wait(p.speed))
+								}
+							case '+', '=':
+								p.setSpeed(&delay, timer, paused, now, min(maxSpeed, p.speed * speedStep))
+							case '-', '_':
+								p.setSpeed(&delay, timer, paused, now, max(minSpeed, p.speed / speedStep))
+						}
+					case <-timer.C:
+						break wait
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// setSpeed changes the playback speed at now. If the current frame is not paused, the rest of its delay is rescheduled at the new speed
+func (p *player) setSpeed(delay *frameDelay, timer *time.Timer, paused bool, now time.Time, speed float64) {
+	if !paused {
+		delay.pause(now, p.speed)
+		delay.resume(now)
+		timer.Reset(delay.wait(speed))
+	}
+	p.speed = speed
+}
+
+/*
+frameDelay tracks how much of the delay of a frame is left, as playback is paused and the speed changes.
+*/
+type frameDelay struct {
+	// remaining is the delay left (at a speed of 1) when the count down was last resumed
+	remaining	time.Duration
+	// resumed is when the count down was last resumed
+	resumed		time.Time
+}
+
+// pause stops the count down at now, having played at speed since it was resumed
+func (d *frameDelay) pause(now time.Time, speed float64) {
+	d.remaining = max(0, d.remaining - time.Duration(float64(now.Sub(d.resumed)) * speed))
+}
+
+// resume starts the count down again at now
+func (d *frameDelay) resume(now time.Time) {
+	d.resumed = now
+}
+
+// wait returns how long the rest of the delay takes at speed, from when the count down was resumed
+func (d *frameDelay) wait(speed float64) time.Duration {
+	return time.Duration(float64(d.remaining) / speed)
+}
+
+// playCount converts a GIF loop count (see asciiart.Animation) to the number of times to play the animation, where 0 plays forever
+func playCount(loopCount int) int {
+	switch {
+		case loopCount == 0:
+			return 0
+		case loopCount < 0:
+			return 1
+		default:
+			return loopCount + 1
+	}
+}
+
+/*
+enableKeyInput switches the terminal to unbuffered input without echo, so single key presses can be read. It returns a function that restores the previous terminal settings, and whether key presses can be read. If stdin is not a terminal (or stty is not available), the terminal is left as is.
+*/
+func enableKeyInput() (func(), bool) {
+	saved, err := stty("-g")
+	if err != nil {
+		return func() {}, false
+	}
+
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return func() {}, false
+	}
+
+	return func() {
+		stty(strings.TrimSpace(saved))
+	}, true
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// readKeys sends every byte read from stdin to keys, until stdin is closed
+func readKeys(keys chan<- byte) {
+	r := bufio.NewReader(os.Stdin)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		keys <- b
+	}
+}
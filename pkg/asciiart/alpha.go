@@ -0,0 +1,103 @@
+package asciiart
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// alphaPolicies is the private struct that functions as a namespace for the enum AlphaPolicy
+type alphaPolicies struct { }
+
+// AlphaPolicies is the public instance of alphaPolicies. Do not reassign this variable
+var AlphaPolicies = alphaPolicies{}
+
+type AlphaPolicy int
+
+/*
+Multiply signals to the converter to scale the luminosity and colour of each character by its alpha. Transparent regions become black, and are rendered with the same character as a black pixel. This is the default.
+*/
+func (p alphaPolicies) Multiply() AlphaPolicy {
+	return AlphaPolicy(0)
+}
+
+/*
+Composite signals to the converter to composite the image onto AlphaBackground before converting it, as if the image was drawn on a canvas of that colour. See WithAlphaBackground()
+*/
+func (p alphaPolicies) Composite() AlphaPolicy {
+	return AlphaPolicy(1)
+}
+
+/*
+Transparent signals to the converter to treat characters with an alpha below AlphaThreshold as fully transparent: they are emitted as a space without any colour codes, so they blend into any terminal background. Every other character is treated as fully opaque. See WithAlphaThreshold()
+*/
+func (p alphaPolicies) Transparent() AlphaPolicy {
+	return AlphaPolicy(2)
+}
+
+/*
+applyAlphaPolicy prepares the transparency of img according to AlphaPolicy:
+	- Multiply returns img as is. MapLuminosity() and the color mappers already scale by alpha.
+	- Composite returns an opaque copy of img drawn over AlphaBackground.
+	- Transparent returns a copy of img where every pixel is either fully transparent (below AlphaThreshold) or fully opaque.
+
+Returns ctx.Err() if ctx is cancelled before the copy is finished.
+*/
+func (a *AsciiConverter) applyAlphaPolicy(ctx context.Context, img image.Image) (image.Image, error) {
+	bounds := img.Bounds()
+	rect := image.Rect(0, 0, bounds.Dx(), bounds.Dy())
+
+	switch a.AlphaPolicy {
+		case AlphaPolicies.Multiply():
+			return img, nil
+		case AlphaPolicies.Composite():
+			bg := a.AlphaBackground
+			if bg == nil {
+				bg = color.Black
+			}
+
+			composited := image.NewRGBA(rect)
+			draw.Draw(composited, rect, image.NewUniform(bg), image.Point{}, draw.Src)
+			draw.Draw(composited, rect, img, bounds.Min, draw.Over)
+
+			return composited, nil
+		case AlphaPolicies.Transparent():
+			thresholded := image.NewNRGBA(rect)
+			read := nrgbaReader(img)
+
+			err := a.parallelRows(ctx, bounds.Dy(), func(y int) {
+				for x := range bounds.Dx() {
+					c := read(bounds.Min.X + x, bounds.Min.Y + y)
+					if int(c.A) < a.AlphaThreshold {
+						// Leave the pixel as the zero value (fully transparent)
+						continue
+					}
+
+					c.A = 255
+					thresholded.SetNRGBA(x, y, c)
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return thresholded, nil
+		default:
+			msg := fmt.Sprintf("Unknown alpha policy provided: %d", a.AlphaPolicy)
+			panic(msg)
+	}
+}
+
+/*
+isTransparentCell reports whether the character at x, y should be emitted as a blank space with no colour codes. This is only ever the case with AlphaPolicies.Transparent().
+*/
+func (a *AsciiConverter) isTransparentCell(lumProv LuminosityProvider, x, y int) bool {
+	if a.AlphaPolicy != AlphaPolicies.Transparent() {
+		return false
+	}
+
+	_, _, _, alpha := lumProv.At(x, y).RGBA()
+	return alpha == 0
+}
@@ -0,0 +1,128 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// alphaRamp returns a 4x1 image of black at alpha 0, 100, 200 and 255
+func alphaRamp() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	for x, alpha := range []uint8{0, 100, 200, 255} {
+		img.SetNRGBA(x, 0, color.NRGBA{A: alpha})
+	}
+	return img
+}
+
+func TestAlphaPolicies(t *testing.T) {
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+
+	tests := []struct {
+		name		string
+		opts		[]AsciiOption
+		wantLum		[]int
+		wantAlpha	[]uint32
+	}{
+		{
+			name: "multiply keeps alpha",
+			opts: []AsciiOption{WithAlphaPolicy(AlphaPolicies.Multiply())},
+			wantLum: []int{0, 0, 0, 0},
+			wantAlpha: []uint32{0, 100, 200, 255},
+		},
+		{
+			name: "composite onto white",
+			opts: []AsciiOption{WithAlphaPolicy(AlphaPolicies.Composite()), WithAlphaBackground(white)},
+			// Black drawn over white at 0, 100/255, 200/255 and full opacity
+			wantLum: []int{255, 155, 55, 0},
+			wantAlpha: []uint32{255, 255, 255, 255},
+		},
+		{
+			name: "composite defaults to black",
+			opts: []AsciiOption{WithAlphaPolicy(AlphaPolicies.Composite()), WithAlphaBackground(nil)},
+			wantLum: []int{0, 0, 0, 0},
+			wantAlpha: []uint32{255, 255, 255, 255},
+		},
+		{
+			name: "transparent thresholds alpha",
+			opts: []AsciiOption{WithAlphaPolicy(AlphaPolicies.Transparent()), WithAlphaThreshold(128)},
+			wantLum: []int{0, 0, 0, 0},
+			wantAlpha: []uint32{0, 0, 255, 255},
+		},
+		{
+			name: "transparent threshold is inclusive",
+			opts: []AsciiOption{WithAlphaPolicy(AlphaPolicies.Transparent()), WithAlphaThreshold(100)},
+			wantLum: []int{0, 0, 0, 0},
+			wantAlpha: []uint32{0, 255, 255, 255},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lumImg := New(tt.opts...).MapLuminosity(alphaRamp())
+
+			for x := range 4 {
+				if got := lumImg.LuminosityAt(x, 0); got != tt.wantLum[x] {
+					t.Errorf("luminosity at %d = %d, want %d", x, got, tt.wantLum[x])
+				}
+
+				_, _, _, alpha := lumImg.At(x, 0).RGBA()
+				if got := alpha >> 8; got != tt.wantAlpha[x] {
+					t.Errorf("alpha at %d = %d, want %d", x, got, tt.wantAlpha[x])
+				}
+			}
+		})
+	}
+}
+
+func TestMultiplyScalesLuminosityByAlpha(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 0})
+	img.SetNRGBA(1, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 128})
+	img.SetNRGBA(2, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+
+	lumImg := New().MapLuminosity(img)
+	lums := []int{lumImg.LuminosityAt(0, 0), lumImg.LuminosityAt(1, 0), lumImg.LuminosityAt(2, 0)}
+
+	if lums[0] != 0 || lums[2] != 255 || lums[1] <= 0 || lums[1] >= 255 {
+		t.Errorf("luminosity of white at alpha 0, 128, 255 = %v, want 0, between, 255", lums)
+	}
+}
+
+func TestTransparentCells(t *testing.T) {
+	a := New(
+		WithAlphaPolicy(AlphaPolicies.Transparent()),
+		WithAlphaThreshold(128),
+		WithSobel(false),
+		WithOutputAspectRatio(1),
+	)
+	canvas := a.ConvertToGrid(alphaRamp(), 4, 4)
+
+	for x, want := range []bool{true, true, false, false} {
+		cell := canvas.Cells[0][x]
+		if cell.Transparent != want {
+			t.Errorf("cell %d Transparent = %v, want %v", x, cell.Transparent, want)
+		}
+		if cell.Transparent && cell.Rune != ' ' {
+			t.Errorf("transparent cell %d rune = %q, want a space", x, cell.Rune)
+		}
+	}
+
+	// Only the transparent policy produces transparent cells
+	canvas = New(WithSobel(false), WithOutputAspectRatio(1)).ConvertToGrid(alphaRamp(), 4, 4)
+	for x, cell := range canvas.Cells[0] {
+		if cell.Transparent {
+			t.Errorf("cell %d is transparent with the multiply policy", x)
+		}
+	}
+}
+
+func TestUnknownAlphaPolicyPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want a panic for an unknown alpha policy")
+		}
+	}()
+
+	New(WithAlphaPolicy(AlphaPolicy(99))).MapLuminosity(alphaRamp())
+}
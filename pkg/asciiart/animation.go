@@ -0,0 +1,116 @@
+package asciiart
+
+import (
+	"bufio"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// gifDefaultDelay is the delay used for GIF frames with a delay of 0 or 1 (1/100ths of a second), matching what browsers do
+const gifDefaultDelay = 10
+
+/*
+Frame is a single converted frame of an animation.
+*/
+type Frame struct {
+	Canvas		*Canvas
+	// Delay is how long the frame is shown before the next frame
+	Delay		time.Duration
+}
+
+// gifFrameDelay converts a GIF delay (1/100ths of a second) to a duration
+func gifFrameDelay(delay int) time.Duration {
+	if delay <= 1 {
+		delay = gifDefaultDelay
+	}
+	return time.Duration(delay) * 10 * time.Millisecond
+}
+
+/*
+Animation is an animated image converted frame by frame. See ConvertAnimation()
+*/
+type Animation struct {
+	Frames		[]Frame
+	/*
+	LoopCount is the number of times the animation repeats, following the convention of image/gif:
+		- 0 loops forever
+		- -1 plays the animation once
+		- n > 0 plays the animation n + 1 times
+	*/
+	LoopCount	int
+}
+
+/*
+ConvertAnimation decodes every frame of an animated GIF from r and converts each of them (see ConvertToGrid()). Any other image format is converted as a single frame animation.
+
+Frames are composited like a browser would: each frame is drawn over the previous frames, then disposed of according to its disposal method before the next frame is drawn:
+	- gif.DisposalNone (or unspecified) leaves the frame in place
+	- gif.DisposalBackground clears the area of the frame to transparent
+	- gif.DisposalPrevious restores the area of the frame to what it was before the frame was drawn
+
+GIF delays of 0 or 1 (1/100ths of a second) are treated as 10, as browsers do.
+*/
+func (a *AsciiConverter) ConvertAnimation(r io.Reader, targetWidth, targetHeight int) (*Animation, error) {
+	br := bufio.NewReader(r)
+
+	// Sniff the header, since image.Decode() only returns the first frame of a GIF
+	header, _ := br.Peek(6)
+	if string(header) != "GIF87a" && string(header) != "GIF89a" {
+		img, _, err := image.Decode(br)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Animation {
+			Frames: []Frame{{Canvas: a.ConvertToGrid(img, targetWidth, targetHeight)}},
+			LoopCount: -1,
+		}, nil
+	}
+
+	g, err := gif.DecodeAll(br)
+	if err != nil {
+		return nil, err
+	}
+
+	screen := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	// previous holds the area of the screen under the current frame, for gif.DisposalPrevious
+	var previous *image.RGBA
+
+	anim := &Animation {
+		Frames: make([]Frame, 0, len(g.Image)),
+		LoopCount: g.LoopCount,
+	}
+
+	for i, img := range g.Image {
+		bounds := img.Bounds()
+
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, screen, bounds.Min, draw.Src)
+		}
+
+		draw.Draw(screen, bounds, img, bounds.Min, draw.Over)
+
+		anim.Frames = append(anim.Frames, Frame {
+			Canvas: a.ConvertToGrid(screen, targetWidth, targetHeight),
+			Delay: gifFrameDelay(g.Delay[i]),
+		})
+
+		switch disposal {
+			case gif.DisposalBackground:
+				draw.Draw(screen, bounds, image.Transparent, image.Point{}, draw.Src)
+			case gif.DisposalPrevious:
+				draw.Draw(screen, bounds, previous, bounds.Min, draw.Src)
+		}
+	}
+
+	return anim, nil
+}
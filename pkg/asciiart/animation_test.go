@@ -0,0 +1,147 @@
+package asciiart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testGIFPalette = color.Palette{color.Black, color.White, color.Transparent}
+
+// gifFrame returns a frame covering rect, filled with the palette index idx
+func gifFrame(rect image.Rectangle, idx uint8) *image.Paletted {
+	img := image.NewPaletted(rect, testGIFPalette)
+	for i := range img.Pix {
+		img.Pix[i] = idx
+	}
+	return img
+}
+
+// encodeGIF encodes g, failing the test on error
+func encodeGIF(t *testing.T, g *gif.GIF) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// frameCells describes a row of cells as a string: ' ' for black, '$' for white and '_' for transparent
+func frameCells(row []Cell) string {
+	var sb strings.Builder
+	for _, cell := range row {
+		if cell.Transparent {
+			sb.WriteByte('_')
+		} else {
+			sb.WriteRune(cell.Rune)
+		}
+	}
+	return sb.String()
+}
+
+func TestConvertAnimationDisposal(t *testing.T) {
+	const black, white = 0, 1
+
+	g := &gif.GIF {
+		Image: []*image.Paletted{
+			gifFrame(image.Rect(0, 0, 4, 1), black),
+			// Cleared to transparent once shown
+			gifFrame(image.Rect(0, 0, 2, 1), white),
+			// Restored to black once shown
+			gifFrame(image.Rect(3, 0, 4, 1), white),
+			gifFrame(image.Rect(2, 0, 3, 1), white),
+		},
+		Delay: []int{0, 1, 5, 100},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalPrevious, gif.DisposalNone},
+		LoopCount: 3,
+		Config: image.Config{ColorModel: testGIFPalette, Width: 4, Height: 1},
+	}
+
+	a := New(WithSobel(false), WithOutputAspectRatio(1), WithAlphaPolicy(AlphaPolicies.Transparent()))
+	anim, err := a.ConvertAnimation(encodeGIF(t, g), 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if anim.LoopCount != 3 {
+		t.Errorf("LoopCount = %d, want 3", anim.LoopCount)
+	}
+
+	want := []struct {
+		cells	string
+		delay	time.Duration
+	}{
+		{"    ", 100 * time.Millisecond},
+		{"$$  ", 100 * time.Millisecond},
+		{"__ $", 50 * time.Millisecond},
+		{"__$ ", time.Second},
+	}
+
+	if len(anim.Frames) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(anim.Frames), len(want))
+	}
+	for i, frame := range anim.Frames {
+		if got := frameCells(frame.Canvas.Cells[0]); got != want[i].cells {
+			t.Errorf("frame %d = %q, want %q", i, got, want[i].cells)
+		}
+		if frame.Delay != want[i].delay {
+			t.Errorf("frame %d delay = %v, want %v", i, frame.Delay, want[i].delay)
+		}
+	}
+}
+
+func TestConvertAnimationStillImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gradientImage()); err != nil {
+		t.Fatal(err)
+	}
+
+	a := New(WithSobel(false), WithOutputAspectRatio(1))
+	anim, err := a.ConvertAnimation(&buf, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(anim.Frames) != 1 || anim.LoopCount != -1 {
+		t.Fatalf("got %d frames looping %d times, want 1 frame played once", len(anim.Frames), anim.LoopCount)
+	}
+	if got, want := renderToString(t, a.renderer(), anim.Frames[0].Canvas), a.Convert(gradientImage(), 4, 2); got != want {
+		t.Errorf("frame = %q, want %q", got, want)
+	}
+}
+
+func TestConvertAnimationInvalidInput(t *testing.T) {
+	for name, input := range map[string]string{"not an image": "hello", "truncated gif": "GIF89a\x04\x00"} {
+		if _, err := New().ConvertAnimation(strings.NewReader(input), 4, 4); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+}
+
+func TestConvertAsciicastAnimation(t *testing.T) {
+	g := &gif.GIF {
+		Image: []*image.Paletted{gifFrame(image.Rect(0, 0, 4, 1), 0), gifFrame(image.Rect(0, 0, 4, 1), 1)},
+		Delay: []int{50, 50},
+		Config: image.Config{ColorModel: testGIFPalette, Width: 4, Height: 1},
+	}
+
+	var buf bytes.Buffer
+	if err := New(WithSobel(false), WithOutputAspectRatio(1)).ConvertAsciicast(encodeGIF(t, g), &buf, 4, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want a header, 2 frames and the end event:\n%s", len(lines), buf.String())
+	}
+	if !strings.HasPrefix(lines[2], "[0.5,") || !strings.HasPrefix(lines[3], "[1,") {
+		t.Errorf("event times are wrong:\n%s", buf.String())
+	}
+}
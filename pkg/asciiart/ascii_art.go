@@ -7,10 +7,11 @@ import (
 	_ "image/png"
 
 	"bytes"
+	"context"
 	"image"
+	"image/color"
 	"io"
 	"math"
-	"strings"
 )
 
 const (
@@ -78,6 +79,10 @@ type ColorMapper8BitOptions struct {
 	gStep				[3]int
 	bStep				[3]int
 	greyStep			[3]int
+	// BlackLumUpper is the upper bound (inclusive) for luminosity measured from white, for which pixels are rendered as white (231) on a light background. Unused on a dark background
+	BlackLumUpper		int
+	// WhiteLumLower is the lower bound (inclusive) for luminosity measured from white, for which pixels are rendered as black (16) on a light background. Unused on a dark background
+	WhiteLumLower		int
 }
 
 // downscalingModes is the private struct that functions as a namespace for the enum DownscalingMode
@@ -102,11 +107,40 @@ func (d downscalingModes) IgnoreAspectRatio() DownscalingMode {
 	return DownscalingMode(1) 
 }
 
+// edgeColorModes is the private struct that functions as a namespace for the enum EdgeColorMode
+type edgeColorModes struct { }
+
+// EdgeColorModes is the public instance of edgeColorModes. Do not reassign this variable
+var EdgeColorModes = edgeColorModes{}
+
+type EdgeColorMode int
+
+/*
+Luminosity signals to sobel edge detection to only use the luminosity of each character. This is the default, and the fastest mode, but boundaries between two colours of equal luminosity are not detected.
+*/
+func (e edgeColorModes) Luminosity() EdgeColorMode {
+	return EdgeColorMode(0)
+}
+
+/*
+RGB signals to sobel edge detection to compute gradients on the red, green and blue channels separately, and combine them with the Di Zenzo structure tensor. Isoluminant colour boundaries (e.g. red against green) are detected as edges.
+*/
+func (e edgeColorModes) RGB() EdgeColorMode {
+	return EdgeColorMode(1)
+}
+
+/*
+Lab signals to sobel edge detection to compute gradients on the CIE L*a*b* channels of each character, and combine them with the Di Zenzo structure tensor. Lab is perceptually uniform, so edge strength follows how different two colours look rather than how different their RGB values are.
+*/
+func (e edgeColorModes) Lab() EdgeColorMode {
+	return EdgeColorMode(2)
+}
+
 type AsciiConverter struct {
 	// SobelMagnitudeSqThresholdNormalized provides the minium gMag2 value before an edge is registered as an edge. This field only has an effect if UseSobel is true. See WithSobelMagSquaredThresholdNormalized()
 	SobelMagnitudeSqThresholdNormalized				float64
 
-	// SobelLaplacianMagnitudeThreshold provides the maximum laplacian value for an edge to be considered an edge. See WithSobelLaplacianThresholdNormalized()
+	// SobelLaplacianMagnitudeThreshold provides the maximum absolute laplacian value for an edge to be considered an edge. See WithSobelLaplacianThresholdNormalized()
 	SobelLaplacianThresholdNormalized				float64
 
 	// Will use bold characters to outline edges detected by the sobel edge detection. Because of this, this only has an effect if UseSobel is true, and if the algorithm can actually detect any edges
@@ -120,17 +154,61 @@ type AsciiConverter struct {
 	//DownscalingMode flags to the converter how to downscale the image before any conversion happens. By default, it will ALWAYS downscale with respect to the aspect ratio (DownscalingModes.WithRespectToAspectRatio() [0])
 	DownscalingMode									DownscalingMode
 
+	// AlphaPolicy specifies how transparent pixels are handled. See WithAlphaPolicy()
+	AlphaPolicy										AlphaPolicy
+	// AlphaBackground is the colour transparent pixels are composited onto with AlphaPolicies.Composite(). nil is treated as black. See WithAlphaBackground()
+	AlphaBackground									color.Color
+	// AlphaThreshold is the minimum alpha (0-255) for a character to be drawn with AlphaPolicies.Transparent(). See WithAlphaThreshold()
+	AlphaThreshold									int
+
+	// Brightness is added to every channel (as a fraction of full brightness) before luminosity is computed. 0 leaves the image untouched. See WithBrightness()
+	Brightness										float64
+	// Contrast stretches (> 0) or flattens (< 0) every channel around mid grey before luminosity is computed. 0 leaves the image untouched. See WithContrast()
+	Contrast										float64
+	// Gamma is the gamma correction applied to every channel before luminosity is computed. 1 (or <= 0) leaves the image untouched. See WithGamma()
+	Gamma											float64
+
+	// LuminanceModel specifies how the r, g, b channels are combined into luminosity. See WithLuminanceModel()
+	LuminanceModel									LuminanceModel
+	// LinearLight flags to the converter to decode sRGB to linear light before resampling and computing luminosity. See WithLinearLight()
+	LinearLight										bool
+	// LuminosityFilters is the chain of filters applied (in order) to the luminosity grid before characters are mapped. See WithLuminosityFilters()
+	LuminosityFilters								[]LuminosityFilter
+
 	// UseSobel flags to the converter whether or not sobel edge detection should be used.
 	UseSobel										bool
+	// PreFilters is the chain of filters applied (in order) to the luminosity grid before sobel edge detection. See WithPreFilters()
+	PreFilters										[]LuminosityFilter
+	// EdgeColorMode selects which channels of the image sobel edge detection runs on. See WithEdgeColorMode()
+	EdgeColorMode									EdgeColorMode
+	// EdgeSupersampling is the number of samples per character (along each axis) used for sobel edge detection. Values <= 1 run edge detection on the downscaled image. See WithEdgeSupersampling()
+	EdgeSupersampling								int
 	// The function that converts a luminence value (0-255) to a rune
 	LuminosityMapper									func(lumProv LuminosityProvider, x, y int) rune
 	// The function that converts an approximate gradient to a rune
 	EdgeMapperFactory								func(aspect_ratio float64) func(sobelProv SobelProvider, x, y int) rune
+	// ANSIColorMapper maps the colour of a character onto an ANSI escape sequence. Set it with WithColorMapper() or one of the library color mapper options, which are built for TerminalBackground. Assigning it directly replaces the library mapper
 	ANSIColorMapper									func(lumProv LuminosityProvider, x, y int) (code_id int, fmted_code string)
-
-	// BytesPerCharToReserve is the amount of bytes per character to reserve in the result buffer
+	// colorMapperFactory builds the library color mapper set by the options for a TerminalBackground. It is nil if ANSIColorMapper is a custom mapper. See colorMapper()
+	colorMapperFactory								func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string)
+	// colorMapperBackground is the TerminalBackground ANSIColorMapper was last built for by colorMapperFactory
+	colorMapperBackground							TerminalBackground
+	// Renderer turns the converted Canvas into the output of Convert(). If it is nil (the default), the output is written by ANSIRenderer(ANSIRendererOptions). See WithRenderer()
+	Renderer										Renderer
+	// ANSIRendererOptions configures the ANSI output written when Renderer is nil. See WithANSIRendererOptions()
+	ANSIRendererOptions								ANSIRendererOptions
+	// CellBackgrounds flags to the converter to fill Cell.BG with the colour of the pixel each character was sampled from. See WithCellBackgrounds()
+	CellBackgrounds									bool
+
+	// Parallelism is the number of goroutines each stage of the pipeline is split over. 1 (the default) converts on the calling goroutine, 0 uses runtime.GOMAXPROCS(0). See WithParallelism()
+	Parallelism										int
+
+	// TerminalBackground is the background colour of the terminal the output is displayed on. It inverts the character ramp and the black/white clamps of the library color mappers. See WithTerminalBackground()
+	TerminalBackground								TerminalBackground
+
+	// BytesPerCharToReserve is the amount of bytes per character to reserve in the result buffer. Only used by the conversions that return a string, not ConvertTo()
 	BytesPerCharToReserve							float64
-	// AdditionalBytesPerCharColor is the amount of additional bytes per character to reserve in the result buffer if color is being used
+	// AdditionalBytesPerCharColor is the amount of additional bytes per character to reserve in the result buffer if color is being used. Only used by the conversions that return a string, not ConvertTo()
 	AdditionalBytesPerCharColor 					float64
 }
 
@@ -289,29 +367,46 @@ type SobelProvider interface {
 /*
 NewDefault initializes an asciiart instance with default parameters:
 	- SobelMagnitudeThresholdNormalized: 80000
-	- SobelLaplacianThresholdNormalized: 300
+	- SobelLaplacianThresholdNormalized: 128
 	- SobelOutlineIsBold: true
 	- OutputAspectRatio: 2
+	- AlphaPolicy: AlphaPolicies.Multiply()
+	- AlphaBackground: black
+	- AlphaThreshold: 128
+	- Brightness: 0
+	- Contrast: 0
+	- Gamma: 1
+	- LuminanceModel: LuminanceModels.Rec709()
 	- DownscalingMode: DownscalingModes.WithRespectToAspectRatio() [0]
 	- UseColor: true
 	- UseSobel: true
 	- LuminenceMapper: <default internal luminence mapper>
 	- EdgeMapperFactor: <default internal edge mapper factory>	
 	- ANSIColorMapper: <default internal 4 bit color mapper>:
+	- Renderer: nil (ANSIRenderer())
+	- TerminalBackground: TerminalBackgrounds.Dark()
+	- Parallelism: 1
 	- BytesPerCharToReserve: 3.5
 	- AdditionalBytesPerCharColor: 2
 */
 func NewDefault() *AsciiConverter {
 	return &AsciiConverter {
 		SobelMagnitudeSqThresholdNormalized: 10000,
-		SobelLaplacianThresholdNormalized: 500,
+		SobelLaplacianThresholdNormalized: 128,
 		SobelOutlineIsBold: true,
 		OutputAspectRatio: 2,
+		AlphaPolicy: AlphaPolicies.Multiply(),
+		AlphaBackground: color.Black,
+		AlphaThreshold: 128,
+		Gamma: 1,
+		LuminanceModel: LuminanceModels.Rec709(),
 		DownscalingMode: DownscalingModes.WithRespectToAspectRatio(),
 		UseSobel: true,
 		LuminosityMapper: DefaultLuminenceMapper,
 		EdgeMapperFactory: DefaultEdgeMapperFactory,
 		ANSIColorMapper: defaultColorMapper(),
+		TerminalBackground: TerminalBackgrounds.Dark(),
+		Parallelism: 1,
 		BytesPerCharToReserve: bytesPerCharReserve,
 		AdditionalBytesPerCharColor: ansiAdditionalBytesReserved3Bit,
 	}
@@ -351,39 +446,42 @@ var default3BitOpts = ColorMapper3BitOptions {
 	WhiteLumLower: 200,
 }
 
+var default4BitOpts = ColorMapper4BitOptions {
+	ColorMapper3BitOptions: default3BitOpts,
+	BoldColoredLumLower: 100,
+	BoldBlackLumLower: 40,
+	BoldWhiteLumLower: 240,
+}
+
+var default8BitOpts = ColorMapper8BitOptions {
+	rStep: [3]int{0, 95, 40},
+	gStep: [3]int{0, 95, 40},
+	bStep: [3]int{0, 95, 40},
+	greyStep: [3]int{8, 18, 10},
+	// Only clamp pixels that are almost white or black, so the rest of the palette is still used for pale and dark colours
+	BlackLumUpper: 16,
+	WhiteLumLower: 240,
+}
+
 /*
-defaultColorMapper provides the default configuration for the 3 bit color mapper provided by this library. 99% of terminals should support at least 3 bit color space.
+defaultColorMapper provides the default configuration for the 3 bit color mapper provided by this library. 99% of terminals should support at least 3 bit color space. The mapper is built for a dark background, use WithDefault3BitColorMapper() to follow the TerminalBackground of the converter.
 */
 func Default3BitColorMapper() func(LuminosityProvider, int, int) (int, string) {
-	return default3BitColorMapperFactory(default3BitOpts)
+	return default3BitColorMapperFactory(default3BitOpts, TerminalBackgrounds.Dark())
 }
 
 /*
-Default4BitColorMapper provides the default configuration for the 4 bit color mapper provided by this library. 99% of terminals should support at least 4 bit color space.
+Default4BitColorMapper provides the default configuration for the 4 bit color mapper provided by this library. 99% of terminals should support at least 4 bit color space. The mapper is built for a dark background, use WithDefault4BitColorMapper() to follow the TerminalBackground of the converter.
 */
 func Default4BitColorMapper() func(LuminosityProvider, int, int) (int, string) {
-	opts := ColorMapper4BitOptions {
-		ColorMapper3BitOptions: default3BitOpts,
-		BoldColoredLumLower: 100,
-		BoldBlackLumLower: 40,
-		BoldWhiteLumLower: 240,
-	}
-
-	return default4BitColorMapperFactory(opts)
+	return default4BitColorMapperFactory(default4BitOpts, TerminalBackgrounds.Dark())
 }
 
 /*
-Default8BitColorMapper provides the default configuration for the 8 bit color mapper provided by this library. 95%+ of terminals should support at least 8 bit color space.
+Default8BitColorMapper provides the default configuration for the 8 bit color mapper provided by this library. 95%+ of terminals should support at least 8 bit color space. The mapper is built for a dark background, use WithDefault8BitColorMapper() to follow the TerminalBackground of the converter.
 */
 func Default8BitColorMapper() func(LuminosityProvider, int, int) (int, string) {
-	opts := ColorMapper8BitOptions {
-		rStep: [3]int{0, 95, 40},
-		gStep: [3]int{0, 95, 40},
-		bStep: [3]int{0, 95, 40},
-		greyStep: [3]int{8, 18, 10},
-	}
-
-	return default8BitColorMapperFactory(opts)
+	return default8BitColorMapperFactory(default8BitOpts, TerminalBackgrounds.Dark())
 }
 
 /*
@@ -484,6 +582,8 @@ ConvertReader takes an io.Reader that can read the bytes of an image. Image form
 	)
 
 ConvertReader uses `image.Decode()` under the hood, so it is important to register file formats so the image module knows how to decode the bytes.
+
+Returns any error from decoding the image, or from the Renderer.
 */
 func (a *AsciiConverter) ConvertReader(r io.Reader, targetWidth, targetHeight int) (string, error) {
 	img, _, err := image.Decode(r)
@@ -491,7 +591,7 @@ func (a *AsciiConverter) ConvertReader(r io.Reader, targetWidth, targetHeight in
 		return "", err
 	}
 
-	return a.Convert(img, targetWidth, targetHeight), nil
+	return a.ConvertContext(context.Background(), img, targetWidth, targetHeight)
 }
 
 /*
@@ -514,6 +614,25 @@ func (a *AsciiConverter) ConvertBytes(b []byte, targetWidth, targetHeight int) (
 	return a.ConvertReader(bytes.NewReader(b), targetWidth, targetHeight)
 }
 
+/*
+ConvertReaderContext is ConvertReader(), but stops and returns ctx.Err() once ctx is cancelled or its deadline passes. See ConvertContext()
+*/
+func (a *AsciiConverter) ConvertReaderContext(ctx context.Context, r io.Reader, targetWidth, targetHeight int) (string, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return "", err
+	}
+
+	return a.ConvertContext(ctx, img, targetWidth, targetHeight)
+}
+
+/*
+ConvertBytesContext is ConvertBytes(), but stops and returns ctx.Err() once ctx is cancelled or its deadline passes. See ConvertContext()
+*/
+func (a *AsciiConverter) ConvertBytesContext(ctx context.Context, b []byte, targetWidth, targetHeight int) (string, error) {
+	return a.ConvertReaderContext(ctx, bytes.NewReader(b), targetWidth, targetHeight)
+}
+
 /*
 DownscaleImage downscales the src image to some targetWidth/targetHeight, however, does it in different ways depending on the DownscalingMode. 
 
@@ -530,9 +649,18 @@ In DownscalingModes.IgnoreAspectRatio() mode:
 Alternatively, if you want to downscale directly to the targetWidth/targetHeight, set the DownscalingMode = to DownscalingModes.IgnoreAspectRatio
 That will signal the function to always downscale to the target resolution 
 
-Returns the downscaled image, and the effective aspect ratio. The effective aspect ratio is should be roughly equal to the original aspect ratio, but may differ because of integer clamping. Use the effective aspect ratio to adjust Sobel thresholds or gradient correction, since the sampling grid may differ slightly from OutputAspectRatio due to integer rounding.
+Returns the downscaled image, and the effective aspect ratio. The effective aspect ratio is the aspect ratio of a single character's cell in the source image (cell height / cell width), and should be roughly equal to OutputAspectRatio, but may differ because of integer clamping. Use the effective aspect ratio to adjust Sobel thresholds or gradient correction, since the sampling grid may differ slightly from OutputAspectRatio due to integer rounding.
+
+NOTE: Before the laplacian was normalized for the cell aspect ratio, the second return value was the aspect ratio of the downscaled grid (newWidth / newHeight), which depends on the shape of the image. It is now the aspect ratio of a single cell, which only depends on OutputAspectRatio and rounding, and is what ApplySobel() and CellGenWithSobel() expect.
 */
 func (a *AsciiConverter) DownscaleImage(src image.Image, targetWidth, targetHeight int) (image.Image, float64) {
+	// context.Background() is never cancelled, so there is no error
+	downscaledImg, effectiveAspectRatio, _ := a.downscaleImage(context.Background(), src, targetWidth, targetHeight)
+	return downscaledImg, effectiveAspectRatio
+}
+
+// downscaleImage is DownscaleImage(), stopping with ctx.Err() if ctx is cancelled
+func (a *AsciiConverter) downscaleImage(ctx context.Context, src image.Image, targetWidth, targetHeight int) (image.Image, float64, error) {
 	var newWidth, newHeight int
 	srcBounds := src.Bounds()
 	srcWidth, srcHeight := srcBounds.Dx(), srcBounds.Dy()
@@ -575,43 +703,120 @@ func (a *AsciiConverter) DownscaleImage(src image.Image, targetWidth, targetHeig
 		panic("Downscaled height of 0 is undefined behaviour. Set a valid targetHeight")
 	}
 	
-	downscaledImg := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	downscaledImg, err := a.resampleImage(ctx, src, newWidth, newHeight)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Each character covers (srcWidth / newWidth) x (srcHeight / newHeight) source pixels
+	cellWidth := float64(srcWidth) / float64(newWidth)
+	cellHeight := float64(srcHeight) / float64(newHeight)
+
+	return downscaledImg, cellHeight / cellWidth, nil
+}
+
+/*
+resampleImage samples src onto a new newWidth x newHeight image. By default it uses nearest neighbour sampling. If LinearLight is set, every source pixel covered by a destination pixel is averaged in linear light instead (see resampleImageLinear()).
+
+Returns ctx.Err() if ctx is cancelled before every pixel is sampled.
+*/
+func (a *AsciiConverter) resampleImage(ctx context.Context, src image.Image, newWidth, newHeight int) (image.Image, error) {
+	if a.LinearLight {
+		resampledImg, err := a.resampleImageLinear(ctx, src, newWidth, newHeight)
+		if err != nil {
+			return nil, err
+		}
+		return resampledImg, nil
+	}
+
+	srcBounds := src.Bounds()
+	srcWidth, srcHeight := srcBounds.Dx(), srcBounds.Dy()
+
+	resampledImg := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	read := pixelReader(src)
 	
-	// Write pixels to the downscaled image
-	for x := range newWidth {
-		for y := range newHeight {
-			srcX := int(float64(x) * float64(srcWidth) / float64(newWidth))
-			srcY := int(float64(y) * float64(srcHeight) / float64(newHeight))
+	// Write pixels straight into the resampled image. This is the same as resampledImg.Set(x, y, src.At(srcX, srcY)), without allocating a colour per pixel
+	err := a.parallelRows(ctx, newHeight, func(y int) {
+		srcY := srcBounds.Min.Y + int(float64(y) * float64(srcHeight) / float64(newHeight))
+		row := resampledImg.Pix[y * resampledImg.Stride : y * resampledImg.Stride + newWidth * 4]
+
+		for x := range newWidth {
+			srcX := srcBounds.Min.X + int(float64(x) * float64(srcWidth) / float64(newWidth))
 
-			c := src.At(srcX, srcY)
+			r, g, b, alpha := read(srcX, srcY)
 
-			downscaledImg.Set(x, y, c)
+			row[x * 4] = uint8(r >> 8)
+			row[x * 4 + 1] = uint8(g >> 8)
+			row[x * 4 + 2] = uint8(b >> 8)
+			row[x * 4 + 3] = uint8(alpha >> 8)
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return downscaledImg, float64(newWidth) / float64(newHeight)
+	return resampledImg, nil
 }
 
 /*
-MapLuminosity returns the default implementation of LuminosityProvider from an image by precalculating all luminosity values and storing it.
+MapLuminosity returns the default implementation of LuminosityProvider from an image by precalculating all luminosity values and storing it. The AlphaPolicy, then the Gamma, Contrast and Brightness adjustments are applied to the image first, so the provider's colours and luminosity are both corrected. The LuminosityFilters are then applied (in order) to the computed luminosity.
 */
 func (a *AsciiConverter) MapLuminosity(img image.Image) defaultLuminosityProvider {
+	// context.Background() is never cancelled, so there is no error
+	lumImg, _ := a.mapLuminosity(context.Background(), img)
+	return lumImg
+}
+
+// mapLuminosity is MapLuminosity(), stopping with ctx.Err() if ctx is cancelled
+func (a *AsciiConverter) mapLuminosity(ctx context.Context, img image.Image) (defaultLuminosityProvider, error) {
+	img, err := a.applyAlphaPolicy(ctx, img)
+	if err != nil {
+		return defaultLuminosityProvider{}, err
+	}
+
+	img, err = a.adjustTone(ctx, img)
+	if err != nil {
+		return defaultLuminosityProvider{}, err
+	}
+
 	lumImg := makeDefaultLuminosityImage(img)
 	
 	bounds := img.Bounds()
 	width, height := bounds.Dx(), bounds.Dy()
+	read := pixelReader(img)
 
-	for x := range width {
-		for y := range height {
-			r, g, b, a := img.At(x, y).RGBA()
-			r8, g8, b8, a8 := r >> 8, g >> 8, b >> 8, a >> 8
-			// Lum approximation. Also scale the luminosity based on the alpha channel
-			lum := int((r8 * 2126 + g8 * 7152 + b8 * 722) / 10000 * a8 / 255)
+	err = a.parallelRows(ctx, height, func(y int) {
+		for x := range width {
+			r, g, b, alpha := read(x, y)
+			r8, g8, b8, a8 := r >> 8, g >> 8, b >> 8, alpha >> 8
+
+			var lum int
+			if a.LinearLight {
+				// Weight the channels in linear light, then encode back to sRGB so the luminosity follows perceived brightness
+				lin := a.LuminanceModel.LuminosityLinear(srgbDecodeTable[r8], srgbDecodeTable[g8], srgbDecodeTable[b8])
+				lum = int(linearToSRGB8(lin)) * int(a8) / 255
+			} else {
+				// Lum approximation. Also scale the luminosity based on the alpha channel
+				lum = a.LuminanceModel.Luminosity8(int(r8), int(g8), int(b8)) * int(a8) / 255
+			}
 			lumImg.LuminositySet(x, y, lum)
 		}
+	})
+	if err != nil {
+		return defaultLuminosityProvider{}, err
 	}
 
-	return lumImg
+	for _, filter := range a.LuminosityFilters {
+		if err := ctx.Err(); err != nil {
+			return defaultLuminosityProvider{}, err
+		}
+
+		filtered := makeDefaultLuminosityImage(img)
+		filter(filtered, lumImg)
+		lumImg = filtered
+	}
+
+	return lumImg, nil
 }
 
 func computeGrad(x float64, y float64) float64 {
@@ -626,7 +831,7 @@ func computeGrad(x float64, y float64) float64 {
 	}
 }
 
-func applySobelCentralPixel(lumImg LuminosityProvider, gGrad []float64, gMag2 []int, gLap []float64, x, y int) {
+func sobelCentralPixel(lumImg LuminosityProvider, x, y int, invAspectRatio2 float64) (int, int, float64) {
 	/*
 		
 	Sobel Value for any character can be decomposed into a kernel for the dx and dy components as defined by the following:
@@ -672,19 +877,19 @@ func applySobelCentralPixel(lumImg LuminosityProvider, gGrad []float64, gMag2 []
 	L =		| +1 -2 +1 |
 			|  0 +1  0 |
 
-	However, this doesn't take into account the aspect ratio. Unlike the sobel operations, we will just normalize the value in place by using this kernel instead
+	However, this doesn't take into account the aspect ratio. A character is aspect_ratio times taller than it is wide, so the vertical second derivative is scaled by 1/aspect_ratio^2. Unlike the sobel operations, we will just normalize the value in place by using this kernel instead
 
-			|  0	 1/aspect_ratio^2     0 |
-	L =		| +1 -(2 + 1/aspect_ratio^2) +1 |
-			|  0     1/aspect_ratio^2     0 |
+			|  0	       1/aspect_ratio^2        0 |
+	L =		| +1 -2 * (1 + 1/aspect_ratio^2) +1 |
+			|  0           1/aspect_ratio^2        0 |
 
-	Probably, we could optimise this better. For now it works great.
+	The kernel sums to 0, so flat areas always have a laplacian of 0 regardless of their luminosity, and a luminosity step of d produces a laplacian of +-d on either side of a vertical edge (and +-d/aspect_ratio^2 for a horizontal edge). The result is stored as a float64, since 1/aspect_ratio^2 is rarely a whole number.
+
+	aspect_ratio is the cell aspect ratio returned by DownscaleImage(), and is passed in as invAspectRatio2 = 1/aspect_ratio^2.
 
 	See https://en.wikipedia.org/wiki/Sobel_operator for more information about sobel operator
 	*/
 
-	idx := x + y * lumImg.Width()
-
 	gx := -1 * lumImg.LuminosityAt(x-1,y-1) +
 	+1 * lumImg.LuminosityAt(x+1,y-1) +
 	-2 * lumImg.LuminosityAt(x-1,y) +
@@ -699,26 +904,16 @@ func applySobelCentralPixel(lumImg LuminosityProvider, gGrad []float64, gMag2 []
 	+2 * lumImg.LuminosityAt(x,y+1) +
 	+1 * lumImg.LuminosityAt(x+1,y+1)
 
-	// Normally, we would have to scale the gMag2 to account for the aspect ratio.
-	cur_gMag2 := gx * gx + gy * gy
+	l := invAspectRatio2 * float64(lumImg.LuminosityAt(x, y-1)) +
+		+1 * float64(lumImg.LuminosityAt(x-1,y)) +
+		-2 * (1 + invAspectRatio2) * float64(lumImg.LuminosityAt(x,y)) +
+		+1 * float64(lumImg.LuminosityAt(x+1,y)) +
+		invAspectRatio2 * float64(lumImg.LuminosityAt(x,y+1))
 
-	gMag2[idx] = cur_gMag2
-	// This gradient is not normalised. Normally you would multiply by dX / dY to account for it.
-	// Instead during lum->char translations, we will multiply the grad thresholds by dY/dX to be more efficient
-	gGrad[idx] = computeGrad(float64(gx), float64(gy))
-
-	invAspectRatio := lumImg.Height() / lumImg.Width()
-
-	l := +(invAspectRatio) * lumImg.LuminosityAt(x, y-1) +
-		+1 * lumImg.LuminosityAt(x-1,y) + 
-		-2 * (2 + 1 * invAspectRatio) * lumImg.LuminosityAt(x,y) +
-		+1 * lumImg.LuminosityAt(x+1,y) +
-		+(invAspectRatio) * lumImg.LuminosityAt(x,y+1)
-
-	gLap[idx] = float64(l)
+	return gx, gy, l
 }
 
-func applySobelPixelSafely(lumImg LuminosityProvider, gGrad []float64, gMag2 []int, gLap []float64, x, y int) {
+func sobelPixelSafely(lumImg LuminosityProvider, x, y int, invAspectRatio2 float64) (int, int, float64) {
 	gx := -1 * lumImg.SafeLuminosityAt(x-1,y-1) +
 	+1 * lumImg.SafeLuminosityAt(x+1,y-1) +
 	-2 * lumImg.SafeLuminosityAt(x-1,y) +
@@ -733,30 +928,88 @@ func applySobelPixelSafely(lumImg LuminosityProvider, gGrad []float64, gMag2 []i
 	+2 * lumImg.SafeLuminosityAt(x,y+1) +
 	+1 * lumImg.SafeLuminosityAt(x+1,y+1)
 
-	// Normally, we would have to scale the gMag2 to account for the aspect ratio.
-	cur_gMag2 := gx * gx + gy * gy
-	idx := x + y * lumImg.Width()
+	l := invAspectRatio2 * float64(lumImg.SafeLuminosityAt(x, y-1)) +
+		+1 * float64(lumImg.SafeLuminosityAt(x-1,y)) +
+		-2 * (1 + invAspectRatio2) * float64(lumImg.SafeLuminosityAt(x,y)) +
+		+1 * float64(lumImg.SafeLuminosityAt(x+1,y)) +
+		invAspectRatio2 * float64(lumImg.SafeLuminosityAt(x,y+1))
 
-	gMag2[idx] = cur_gMag2
-	// This gradient is not normalised. Normally you would multiply by dX / dY to account for it.
-	// Instead during lum->char translations, we will multiply the grad thresholds by dY/dX to be more efficient
-	gGrad[idx] = computeGrad(float64(gx), float64(gy))
+	return gx, gy, l
+}
 
-	invAspectRatio := lumImg.Height() / lumImg.Width()
+/*
+applySobelPixel runs the sobel kernel over each channel at x, y and stores the gradient, magnitude squared and laplacian.
 
-	l := +(invAspectRatio) * lumImg.SafeLuminosityAt(x, y-1) +
-		+1 * lumImg.SafeLuminosityAt(x-1,y) + 
-		-2 * (2 + 1 * invAspectRatio) * lumImg.SafeLuminosityAt(x,y) +
-		+1 * lumImg.SafeLuminosityAt(x+1,y) +
-		+(invAspectRatio) * lumImg.SafeLuminosityAt(x,y+1)
+With a single channel, the magnitude squared is simply gx^2 + gy^2. With multiple channels the gradients are combined using the Di Zenzo structure tensor: the magnitude squared is the largest eigenvalue of the summed tensor (scaled by magNorm), the gradient follows its eigenvector, and the laplacian is taken from the channel with the strongest gradient.
+*/
+func applySobelPixel(
+	channels []LuminosityProvider,
+	kernel func(lumImg LuminosityProvider, x, y int, invAspectRatio2 float64) (int, int, float64),
+	gGrad []float64, gMag2 []int, gLap []float64,
+	magNorm, invAspectRatio2 float64,
+	x, y int,
+) {
+	idx := x + y * channels[0].Width()
+
+	if len(channels) == 1 {
+		gx, gy, l := kernel(channels[0], x, y, invAspectRatio2)
+
+		// Normally, we would have to scale the gMag2 to account for the aspect ratio.
+		gMag2[idx] = gx * gx + gy * gy
+		// This gradient is not normalised. Normally you would multiply by dX / dY to account for it.
+		// Instead during lum->char translations, we will multiply the grad thresholds by dY/dX to be more efficient
+		gGrad[idx] = computeGrad(float64(gx), float64(gy))
+		gLap[idx] = l
+		return
+	}
+
+	var gxx, gyy, gxy float64
+	strongest := -1
+	for _, channel := range channels {
+		gx, gy, l := kernel(channel, x, y, invAspectRatio2)
 
-	gLap[idx] = float64(l)
+		gxx += float64(gx * gx)
+		gyy += float64(gy * gy)
+		gxy += float64(gx * gy)
+
+		if mag2 := gx * gx + gy * gy; mag2 > strongest {
+			strongest = mag2
+			gLap[idx] = l
+		}
+	}
+
+	// Largest eigenvalue and its direction of the 2x2 tensor [[gxx, gxy], [gxy, gyy]]
+	lambda := (gxx + gyy + math.Sqrt((gxx - gyy) * (gxx - gyy) + 4 * gxy * gxy)) / 2
+	theta := 0.5 * math.Atan2(2 * gxy, gxx - gyy)
+
+	gMag2[idx] = int(lambda * magNorm)
+	gGrad[idx] = computeGrad(math.Cos(theta), math.Sin(theta))
 }
 
 /*
-ApplySobel returns the defaultSobelProvider implementation of SobelProvider from a luminosity provider
+ApplySobel returns the defaultSobelProvider implementation of SobelProvider from a luminosity provider. The sobel kernel is run over the channels selected by EdgeColorMode after the PreFilters chain has been applied to each of them, but the returned provider still reports the unfiltered luminosity of lumImg.
+
+aspect_ratio is the cell aspect ratio (cell height / cell width) returned by DownscaleImage(). It is used to normalize the laplacian, so that SobelLaplacianThresholdNormalized is measured in luminosity for both vertical and horizontal edges.
+
+NOTE: aspect_ratio is a new parameter. Callers of the old ApplySobel(lumImg) should pass the second return value of DownscaleImage(), or OutputAspectRatio if lumImg was not made by DownscaleImage().
 */
-func (a *AsciiConverter) ApplySobel(lumImg LuminosityProvider) defaultSobelProvider {	
+func (a *AsciiConverter) ApplySobel(lumImg LuminosityProvider, aspect_ratio float64) defaultSobelProvider {
+	// context.Background() is never cancelled, so there is no error
+	sobelProv, _ := a.applySobel(context.Background(), lumImg, aspect_ratio)
+	return sobelProv
+}
+
+// applySobel is ApplySobel(), stopping with ctx.Err() if ctx is cancelled
+func (a *AsciiConverter) applySobel(ctx context.Context, lumImg LuminosityProvider, aspect_ratio float64) (defaultSobelProvider, error) {
+	invAspectRatio2 := 1 / (aspect_ratio * aspect_ratio)
+	channels, magNorm := a.edgeChannels(lumImg)
+	for i := range channels {
+		if err := ctx.Err(); err != nil {
+			return defaultSobelProvider{}, err
+		}
+		channels[i] = a.ApplyPreFilters(channels[i])
+	}
+
 	gWidth := lumImg.Width()
 	gHeight := lumImg.Height()
 
@@ -765,145 +1018,114 @@ func (a *AsciiConverter) ApplySobel(lumImg LuminosityProvider) defaultSobelProvi
 	gGrad := make([]float64, gLen)
 	gLap := make([]float64, gLen)
 
-	// Calculate G
-	for y := 1; y < gHeight - 1; y++ {
+	// Calculate G, skipping the first and last rows
+	err := a.parallelRows(ctx, max(0, gHeight - 2), func(row int) {
+		y := row + 1
 		for x := 1; x < gWidth - 1; x++ {
-			applySobelCentralPixel(lumImg, gGrad, gMag2, gLap, x, y)
+			applySobelPixel(channels, sobelCentralPixel, gGrad, gMag2, gLap, magNorm, invAspectRatio2, x, y)
 		}
+	})
+	if err != nil {
+		return defaultSobelProvider{}, err
 	}
 
 	// Apply left/right sides
 	for x := range gWidth {
-		applySobelPixelSafely(lumImg, gGrad, gMag2, gLap, x, 0)
-		applySobelPixelSafely(lumImg, gGrad, gMag2, gLap, x, gHeight - 1)
+		applySobelPixel(channels, sobelPixelSafely, gGrad, gMag2, gLap, magNorm, invAspectRatio2, x, 0)
+		applySobelPixel(channels, sobelPixelSafely, gGrad, gMag2, gLap, magNorm, invAspectRatio2, x, gHeight - 1)
 	}
 
 	// Apply bottom/top (skipping corners that we have already done)
 	for y := 1; y < gHeight - 1; y++ {
-		applySobelPixelSafely(lumImg, gGrad, gMag2, gLap, 0, y)
-		applySobelPixelSafely(lumImg, gGrad, gMag2, gLap, gWidth-1, y)
+		applySobelPixel(channels, sobelPixelSafely, gGrad, gMag2, gLap, magNorm, invAspectRatio2, 0, y)
+		applySobelPixel(channels, sobelPixelSafely, gGrad, gMag2, gLap, magNorm, invAspectRatio2, gWidth-1, y)
 	}
 
-	return makeDefaultSobelProvider(lumImg, gGrad, gMag2, gLap)
+	return makeDefaultSobelProvider(lumImg, gGrad, gMag2, gLap), nil
 }
 
 /*
 ASCIIGenWithSobel converts a SobelProvider to ascii string. If you are not interested in making custom ascii generators, see Convert(), ConvertBytes() and ConvertReader()
+
+It always renders with ANSIRenderer(). See CellGenWithSobel() to use a different Renderer.
 */
 func (a *AsciiConverter) ASCIIGenWithSobel(sobelProv SobelProvider, aspect_ratio float64) string {
-	adjustedGMag2Threshold := int(a.SobelMagnitudeSqThresholdNormalized * (aspect_ratio * aspect_ratio))
-
-	width, height := sobelProv.Width(), sobelProv.Height()
-	// numPixels := width * height
-
-	edgeMapper := a.EdgeMapperFactory(aspect_ratio)
-
-	var bufferSize int
-	// In most cases, we will overallocate by a few hundred bytes to ensure there is no reallocation of the buffer
-	// This is because it cannot be known how much room should be left for the colour ANSI escape sequences
-	bufferSize = int((a.BytesPerCharToReserve + a.AdditionalBytesPerCharColor) * float64(width + 1) * float64(height)) // width + 1 because leave a byte for the new line byte
-
-	var asciiBuilder strings.Builder
-	asciiBuilder.Grow(bufferSize)
-
-	var prevWasBold bool = false
-	var prevCode int = -1
-	var code = -1
-	var escapeStr = ""
-	// Reset everything before we write
-	asciiBuilder.WriteString("\x1b[0m")
-
-	for y := range height {
-		for x := range width {
-			code, escapeStr = a.ANSIColorMapper(sobelProv, x, y)
-			if code != prevCode {
-				prevCode = code
-
-				asciiBuilder.WriteString(escapeStr)
-			}
-
-			// Check if we should use the edge or the luminosity mapper
-			if sobelProv.SobelMag2At(x, y) >= adjustedGMag2Threshold &&
-				math.Abs(sobelProv.SobelLaplacianAt(x, y)) <= a.SobelLaplacianThresholdNormalized {
-					
-				if a.SobelOutlineIsBold && !prevWasBold {
-					prevWasBold = true
-					asciiBuilder.WriteString("\x1b[1m") // Make bold
-				}
-
-				asciiBuilder.WriteRune(edgeMapper(sobelProv, x, y))
-			} else {
-
-				if prevWasBold {
-					prevWasBold = false
-					asciiBuilder.WriteString("\x1b[22m") // Reset bold
-				}
-
-				asciiBuilder.WriteRune(a.LuminosityMapper(sobelProv, x, y))
-			}
-		}
-		asciiBuilder.WriteRune('\n')
-	}
-
-	// Reset all styles
-	asciiBuilder.WriteString("\x1b[0m")
-	
-	return asciiBuilder.String()
+	// The ANSI renderer only fails if writing fails, and writing to a string cannot
+	out, _ := a.renderString(ANSIRenderer(), a.CellGenWithSobel(sobelProv, aspect_ratio))
+	return out
 }
 
 /*
 ASCIIGen takes a LuminosityProvider and generates an ascii string from it. If you are not interested in making custom ascii generators, see Convert(), ConvertBytes() and ConvertReader()
+
+It always renders with ANSIRenderer(). See CellGen() to use a different Renderer.
 */
 func (a *AsciiConverter) ASCIIGen(lumProv LuminosityProvider, aspect_ratio float64) string {
-	width, height := lumProv.Width(), lumProv.Height()
+	// The ANSI renderer only fails if writing fails, and writing to a string cannot
+	out, _ := a.renderString(ANSIRenderer(), a.CellGen(lumProv, aspect_ratio))
+	return out
+}
 
-	var bufferSize int
-	// In most cases, we will overallocate by a few hundred bytes to ensure there is no reallocation of the buffer
-	// This is because it cannot be known how much room should be left for the colour ANSI escape sequences
-	bufferSize = int((a.BytesPerCharToReserve + a.AdditionalBytesPerCharColor) * float64(width + 1) * float64(height)) // width + 1 because leave a byte for the new line byte
+/*
+Convert takes an image and generates an ascii art string with targetWidth and targetHeight parameters. 
 
-	var asciiBuilder strings.Builder
-	asciiBuilder.Grow(bufferSize)
+However, if targetWidth and targetHeight do not follow the OutputAspectRatio, then one of targetWidth and targetHeight will be ignored by default (usually height if you are using OutputAspectRatio = 2 which is standard).
 
-	var prevColor int = -1
+To ignore this behaviour and always convert to target width and height, specify DownscalingMode to be equal to DownscalingModes.IgnoreAspectRatio
 
-	for y := range height {
-		for x := range width {
-			code, escapeStr := a.ANSIColorMapper(lumProv, x, y)
-			if code != prevColor {
-				prevColor = code
+Convert cannot report an error from a custom Renderer, and returns whatever was rendered before it. Use ConvertContext() or ConvertTo() with renderers that can fail.
+*/
+func (a *AsciiConverter) Convert(img image.Image, targetWidth, targetHeight int) string {
+	// The error is only ever set by the renderer, see above
+	out, _ := a.renderString(a.renderer(), a.ConvertToGrid(img, targetWidth, targetHeight))
+	return out
+}
 
-				asciiBuilder.WriteString(escapeStr)
-			}
+/*
+ConvertContext is Convert(), but stops and returns ctx.Err() once ctx is cancelled or its deadline passes, e.g. when the client of a web service disconnects. Cancellation is checked between the stages of the pipeline, and between the rows of every stage, so a cancelled conversion stops promptly without finishing the image.
 
-			asciiBuilder.WriteRune(a.LuminosityMapper(lumProv, x, y))
-		}
-		asciiBuilder.WriteRune('\n')
+Unlike Convert(), it also returns any error from the Renderer.
+*/
+func (a *AsciiConverter) ConvertContext(ctx context.Context, img image.Image, targetWidth, targetHeight int) (string, error) {
+	canvas, err := a.convertToGrid(ctx, img, targetWidth, targetHeight)
+	if err != nil {
+		return "", err
 	}
 
-	asciiBuilder.WriteString("\x1b[0m")
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 
-	
-	return asciiBuilder.String()
+	out, err := a.renderString(a.renderer(), canvas)
+	if err != nil {
+		return "", err
+	}
+
+	return out, nil
 }
 
 /*
-Convert takes an image and generates an ascii art string with targetWidth and targetHeight parameters. 
+ConvertTo converts img like Convert(), but writes the output straight to w instead of building one big string. Use it for large renders, or to write straight into files and HTTP responses. Since no result buffer is needed, BytesPerCharToReserve and AdditionalBytesPerCharColor are not used.
 
-However, if targetWidth and targetHeight do not follow the OutputAspectRatio, then one of targetWidth and targetHeight will be ignored by default (usually height if you are using OutputAspectRatio = 2 which is standard).
+With the default ANSI output (Renderer is nil), the cells are generated one row at a time, and every row is written as soon as it is generated, so the Canvas is never built. Any other Renderer is given the whole Canvas.
 
-To ignore this behaviour and always convert to target width and height, specify DownscalingMode to be equal to DownscalingModes.IgnoreAspectRatio
+Returns any error from the Renderer, or from writing to w.
 */
-func (a *AsciiConverter) Convert(img image.Image, targetWidth, targetHeight int) string {
-	var effectiveAspectRatio float64
-	img, effectiveAspectRatio = a.DownscaleImage(img, targetWidth, targetHeight)
-	lumImg := a.MapLuminosity(img)
+func (a *AsciiConverter) ConvertTo(w io.Writer, img image.Image, targetWidth, targetHeight int) error {
+	if a.Renderer != nil {
+		return a.Renderer(w, a.ConvertToGrid(img, targetWidth, targetHeight))
+	}
 
-	if a.UseSobel {
-		sobelImg := a.ApplySobel(lumImg)
+	// context.Background() is never cancelled, so there is no error
+	canvas, gen, _ := a.convertToRows(context.Background(), img, targetWidth, targetHeight)
 
-		return a.ASCIIGenWithSobel(sobelImg, effectiveAspectRatio)
+	aw := newANSIWriter(w, a.ANSIRendererOptions, canvas.EdgesDetected)
+	row := make([]Cell, canvas.Width)
+	for y := range canvas.Height {
+		clear(row)
+		gen(row, y)
+		aw.writeRow(row)
 	}
 
-	return a.ASCIIGen(lumImg, effectiveAspectRatio)
+	return aw.finish()
 }
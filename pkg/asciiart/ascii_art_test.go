@@ -0,0 +1,279 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// makeTestLuminosity builds a luminosity provider from rows of luminosity, backed by a grey image of the same luminosity
+func makeTestLuminosity(rows [][]int) defaultLuminosityProvider {
+	img := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, lum := range row {
+			img.SetGray(x, y, color.Gray{Y: uint8(lum)})
+		}
+	}
+
+	lumImg := makeDefaultLuminosityImage(img)
+	for y, row := range rows {
+		for x, lum := range row {
+			lumImg.LuminositySet(x, y, lum)
+		}
+	}
+	return lumImg
+}
+
+// verticalStep returns a width x height grid that is lo left of column edgeX, and hi from column edgeX onwards
+func verticalStep(width, height, edgeX, lo, hi int) [][]int {
+	rows := make([][]int, height)
+	for y := range rows {
+		rows[y] = make([]int, width)
+		for x := range rows[y] {
+			rows[y][x] = lo
+			if x >= edgeX {
+				rows[y][x] = hi
+			}
+		}
+	}
+	return rows
+}
+
+// horizontalStep returns a width x height grid that is lo above row edgeY, and hi from row edgeY onwards
+func horizontalStep(width, height, edgeY, lo, hi int) [][]int {
+	rows := make([][]int, height)
+	for y := range rows {
+		rows[y] = make([]int, width)
+		for x := range rows[y] {
+			rows[y][x] = lo
+			if y >= edgeY {
+				rows[y][x] = hi
+			}
+		}
+	}
+	return rows
+}
+
+// verticalLine returns a width x height grid that is lo everywhere except for column lineX, which is hi
+func verticalLine(width, height, lineX, lo, hi int) [][]int {
+	rows := verticalStep(width, height, width, lo, hi)
+	for y := range rows {
+		rows[y][lineX] = hi
+	}
+	return rows
+}
+
+func TestSobelLaplacian(t *testing.T) {
+	tests := []struct {
+		name		string
+		rows		[][]int
+		aspectRatio	float64
+		x, y		int
+		want		float64
+	}{
+		{"flat dark", verticalStep(9, 5, 9, 0, 0), 1, 4, 2, 0},
+		{"flat bright", verticalStep(9, 5, 9, 255, 255), 2, 4, 2, 0},
+		{"step left of edge", verticalStep(9, 5, 5, 0, 200), 1, 4, 2, 200},
+		{"step right of edge", verticalStep(9, 5, 5, 0, 200), 1, 5, 2, -200},
+		{"step away from edge", verticalStep(9, 5, 5, 0, 200), 1, 2, 2, 0},
+		{"vertical step ignores aspect ratio", verticalStep(9, 5, 5, 0, 200), 2, 4, 2, 200},
+		{"horizontal step above edge", horizontalStep(5, 9, 5, 0, 200), 1, 2, 4, 200},
+		{"horizontal step scaled by aspect ratio", horizontalStep(5, 9, 5, 0, 200), 2, 2, 4, 50},
+		{"horizontal step below edge", horizontalStep(5, 9, 5, 0, 200), 2, 2, 5, -50},
+		{"line centre", verticalLine(9, 5, 4, 0, 255), 1, 4, 2, -510},
+		{"line side", verticalLine(9, 5, 4, 0, 255), 1, 3, 2, 255},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(WithEdgeSupersampling(1))
+			sobelProv := a.ApplySobel(makeTestLuminosity(tt.rows), tt.aspectRatio)
+
+			if got := sobelProv.SobelLaplacianAt(tt.x, tt.y); math.Abs(got - tt.want) > 1e-9 {
+				t.Errorf("SobelLaplacianAt(%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSobelLaplacianThreshold(t *testing.T) {
+	tests := []struct {
+		name		string
+		rows		[][]int
+		aspectRatio	float64
+		threshold	float64
+		// wantEdges is the cells of row 2 (or column 2 for horizontal steps) that are edges
+		wantEdges	[]bool
+		horizontal	bool
+	}{
+		{
+			name: "flat has no edges",
+			rows: verticalStep(9, 5, 9, 128, 128),
+			aspectRatio: 1,
+			threshold: 0,
+			wantEdges: []bool{false, false, false, false, false, false, false, false, false},
+		},
+		{
+			name: "soft step passes the default",
+			rows: verticalStep(9, 5, 5, 0, 100),
+			aspectRatio: 1,
+			threshold: 128,
+			wantEdges: []bool{false, false, false, false, true, true, false, false, false},
+		},
+		{
+			name: "hard step fails the default",
+			rows: verticalStep(9, 5, 5, 0, 200),
+			aspectRatio: 1,
+			threshold: 128,
+			wantEdges: []bool{false, false, false, false, false, false, false, false, false},
+		},
+		{
+			name: "hard step passes a threshold equal to its contrast",
+			rows: verticalStep(9, 5, 5, 0, 200),
+			aspectRatio: 1,
+			threshold: 200,
+			wantEdges: []bool{false, false, false, false, true, true, false, false, false},
+		},
+		{
+			name: "line sides pass, line centre fails",
+			rows: verticalLine(9, 5, 4, 0, 100),
+			aspectRatio: 1,
+			threshold: 128,
+			// The centre of the line has no horizontal gradient, so it is never an edge either way
+			wantEdges: []bool{false, false, false, true, false, true, false, false, false},
+		},
+		{
+			name: "hard horizontal step passes with a tall cell",
+			rows: horizontalStep(5, 9, 5, 0, 200),
+			aspectRatio: 2,
+			threshold: 128,
+			wantEdges: []bool{false, false, false, false, true, true, false, false, false},
+			horizontal: true,
+		},
+		{
+			name: "hard horizontal step fails with a square cell",
+			rows: horizontalStep(5, 9, 5, 0, 200),
+			aspectRatio: 1,
+			threshold: 128,
+			wantEdges: []bool{false, false, false, false, false, false, false, false, false},
+			horizontal: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(
+				WithEdgeSupersampling(1),
+				WithSobelMagSquaredThresholdNormalized(1),
+				WithSobelLaplacianThresholdNormalized(tt.threshold),
+			)
+			sobelProv := a.ApplySobel(makeTestLuminosity(tt.rows), tt.aspectRatio)
+			canvas := a.CellGenWithSobel(sobelProv, tt.aspectRatio)
+
+			for i, want := range tt.wantEdges {
+				x, y := i, 2
+				if tt.horizontal {
+					x, y = 2, i
+				}
+
+				if got := canvas.Cells[y][x].IsEdge; got != want {
+					t.Errorf("cell (%d, %d) IsEdge = %v, want %v (laplacian %v)", x, y, got, want, sobelProv.SobelLaplacianAt(x, y))
+				}
+			}
+		})
+	}
+}
+
+func TestDownscaleImageCellAspectRatio(t *testing.T) {
+	tests := []struct {
+		name				string
+		srcWidth, srcHeight	int
+		targetWidth			int
+		targetHeight		int
+		outputAspectRatio	float64
+	}{
+		{"square", 400, 400, 100, 100, 2},
+		{"wide", 800, 200, 100, 100, 2},
+		{"tall", 200, 800, 100, 100, 2},
+		{"wide with square cells", 800, 200, 100, 100, 1},
+		{"uneven rounding", 333, 127, 70, 70, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(WithOutputAspectRatio(tt.outputAspectRatio))
+			src := image.NewGray(image.Rect(0, 0, tt.srcWidth, tt.srcHeight))
+
+			downscaled, aspectRatio := a.DownscaleImage(src, tt.targetWidth, tt.targetHeight)
+			bounds := downscaled.Bounds()
+
+			cellWidth := float64(tt.srcWidth) / float64(bounds.Dx())
+			cellHeight := float64(tt.srcHeight) / float64(bounds.Dy())
+			if want := cellHeight / cellWidth; math.Abs(aspectRatio - want) > 1e-9 {
+				t.Errorf("aspect ratio = %v, want cell height / cell width = %v", aspectRatio, want)
+			}
+
+			// Integer rounding of the grid should only move the cell aspect ratio slightly away from OutputAspectRatio, whatever the shape of the image
+			if math.Abs(aspectRatio - tt.outputAspectRatio) > 0.1 * tt.outputAspectRatio {
+				t.Errorf("aspect ratio = %v, want roughly %v", aspectRatio, tt.outputAspectRatio)
+			}
+		})
+	}
+}
+
+func TestLaplacianThresholdScalesWithNonSquareImage(t *testing.T) {
+	// A wide image with a hard horizontal and vertical edge, downscaled to cells twice as tall as they are wide
+	src := image.NewGray(image.Rect(0, 0, 160, 40))
+	for y := range 40 {
+		for x := range 160 {
+			if x >= 80 || y >= 20 {
+				src.SetGray(x, y, color.Gray{Y: 200})
+			}
+		}
+	}
+
+	a := New(
+		WithEdgeSupersampling(1),
+		WithSobelMagSquaredThresholdNormalized(1),
+		WithSobelLaplacianThresholdNormalized(128),
+	)
+	downscaled, aspectRatio := a.DownscaleImage(src, 40, 40)
+	if aspectRatio < 1.9 || aspectRatio > 2.1 {
+		t.Fatalf("aspect ratio = %v, want roughly 2", aspectRatio)
+	}
+
+	sobelProv := a.ApplySobel(a.MapLuminosity(downscaled), aspectRatio)
+	canvas := a.CellGenWithSobel(sobelProv, aspectRatio)
+
+	// Find the rows and columns either side of each edge from the grid, since the downscaled size depends on rounding
+	edgeX := canvas.Width / 2
+	edgeY := canvas.Height / 2
+	for y := range canvas.Height {
+		if sobelProv.LuminosityAt(0, y) != 0 {
+			edgeY = y
+			break
+		}
+	}
+	for x := range canvas.Width {
+		if sobelProv.LuminosityAt(x, 0) != 0 {
+			edgeX = x
+			break
+		}
+	}
+
+	// The horizontal edge has a laplacian of 200/aspect_ratio^2, so it passes the threshold
+	for _, y := range []int{edgeY - 1, edgeY} {
+		if cell := canvas.Cells[y][2]; !cell.IsEdge {
+			t.Errorf("cell (2, %d) beside the horizontal edge is not an edge (laplacian %v)", y, sobelProv.SobelLaplacianAt(2, y))
+		}
+	}
+
+	// The vertical edge has a laplacian of 200, so it fails the threshold
+	for _, x := range []int{edgeX - 1, edgeX} {
+		y := edgeY / 2
+		if cell := canvas.Cells[y][x]; cell.IsEdge {
+			t.Errorf("cell (%d, %d) beside the vertical edge is an edge (laplacian %v)", x, y, sobelProv.SobelLaplacianAt(x, y))
+		}
+	}
+}
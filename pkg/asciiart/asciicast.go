@@ -0,0 +1,75 @@
+package asciiart
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// asciicastHeader is the first line of an asciicast v2 recording
+type asciicastHeader struct {
+	Version		int		`json:"version"`
+	Width		int		`json:"width"`
+	Height		int		`json:"height"`
+}
+
+/*
+WriteAsciicast writes frames as an asciinema recording (asciicast v2), which can be played with `asciinema play` or embedded with the asciinema player. Every frame is written as an "o" event at the time it appears.
+
+The first frame is drawn in full. Every later frame only redraws the cells that changed from the previous frame (see FrameEncoder), so playback does not flicker and the recording stays small.
+*/
+func WriteAsciicast(w io.Writer, frames []Frame) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	width, height := 0, 0
+	for _, frame := range frames {
+		width = max(width, frame.Canvas.Width)
+		height = max(height, frame.Canvas.Height)
+	}
+
+	// Leave a line for the cursor after the last row, so the terminal never scrolls
+	if err := enc.Encode(asciicastHeader{Version: 2, Width: width, Height: height + 1}); err != nil {
+		return err
+	}
+
+	var elapsed time.Duration
+	var sb strings.Builder
+	frameEnc := NewFrameEncoder(&sb)
+
+	for _, frame := range frames {
+		sb.Reset()
+		if err := frameEnc.Encode(frame.Canvas); err != nil {
+			return err
+		}
+
+		if err := enc.Encode([]any{elapsed.Seconds(), "o", sb.String()}); err != nil {
+			return err
+		}
+
+		elapsed += frame.Delay
+	}
+
+	// An empty event keeps the last frame on screen for its delay
+	if len(frames) > 0 {
+		if err := enc.Encode([]any{elapsed.Seconds(), "o", ""}); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+/*
+ConvertAsciicast converts every frame of an animated GIF read from r (see ConvertAnimation()), and writes them to w as an asciinema recording. See WriteAsciicast(). Other image formats are written as a recording of a single frame.
+*/
+func (a *AsciiConverter) ConvertAsciicast(r io.Reader, w io.Writer, targetWidth, targetHeight int) error {
+	anim, err := a.ConvertAnimation(r, targetWidth, targetHeight)
+	if err != nil {
+		return err
+	}
+
+	return WriteAsciicast(w, anim.Frames)
+}
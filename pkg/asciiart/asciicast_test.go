@@ -0,0 +1,104 @@
+package asciiart
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAsciicast(t *testing.T) {
+	first := testCanvas([]Cell{{Rune: 'a'}, {Rune: 'b'}}, []Cell{{Rune: 'c'}, {Rune: 'd'}})
+	second := testCanvas([]Cell{{Rune: 'a'}, {Rune: 'b'}}, []Cell{{Rune: 'c'}, {Rune: 'X'}})
+
+	tests := []struct {
+		name	string
+		frames	[]Frame
+		want	string
+	}{
+		{
+			name: "first frame in full, then diffs",
+			frames: []Frame{{Canvas: first, Delay: 100 * time.Millisecond}, {Canvas: second, Delay: 250 * time.Millisecond}},
+			want: `{"version":2,"width":2,"height":3}` + "\n" +
+				`[0,"o","\u001b[0m\u001b[2J\u001b[1;1Hab\u001b[2;1Hcd\u001b[3;1H"]` + "\n" +
+				`[0.1,"o","\u001b[2;2HX\u001b[3;1H"]` + "\n" +
+				`[0.35,"o",""]` + "\n",
+		},
+		{
+			name: "unchanged frames are empty events",
+			frames: []Frame{{Canvas: first, Delay: time.Second}, {Canvas: first, Delay: time.Second}},
+			want: `{"version":2,"width":2,"height":3}` + "\n" +
+				`[0,"o","\u001b[0m\u001b[2J\u001b[1;1Hab\u001b[2;1Hcd\u001b[3;1H"]` + "\n" +
+				`[1,"o",""]` + "\n" +
+				`[2,"o",""]` + "\n",
+		},
+		{
+			name: "no frames",
+			want: `{"version":2,"width":0,"height":1}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteAsciicast(&buf, tt.frames); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteAsciicastSizeFitsLargestFrame(t *testing.T) {
+	small := testCanvas([]Cell{{Rune: 'a'}})
+	large := testCanvas([]Cell{{Rune: 'a'}, {Rune: 'b'}, {Rune: 'c'}}, []Cell{{Rune: 'd'}, {Rune: 'e'}, {Rune: 'f'}})
+
+	var buf bytes.Buffer
+	if err := WriteAsciicast(&buf, []Frame{{Canvas: small}, {Canvas: large}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var header asciicastHeader
+	if err := json.Unmarshal([]byte(strings.SplitN(buf.String(), "\n", 2)[0]), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Width != 3 || header.Height != 3 {
+		t.Errorf("header size = %dx%d, want 3x3", header.Width, header.Height)
+	}
+}
+
+func TestConvertAsciicastSingleFrame(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, gradientImage()); err != nil {
+		t.Fatal(err)
+	}
+
+	a := New(WithSobel(false), WithOutputAspectRatio(1))
+	var buf bytes.Buffer
+	if err := a.ConvertAsciicast(&img, &buf, 4, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want a header, the frame and the end event:\n%s", len(lines), buf.String())
+	}
+
+	var event []any
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	// The frame draws the same characters as Convert()
+	want := strings.Split(strings.TrimSuffix(a.Convert(gradientImage(), 4, 2), "\n"), "\n")
+	frame := event[2].(string)
+	for _, row := range want {
+		if !strings.Contains(frame, row) {
+			t.Errorf("frame %q does not contain row %q", frame, row)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package asciiart
+
+import (
+	"strconv"
+	"strings"
+)
+
+// terminalBackgrounds is the private struct that functions as a namespace for the enum TerminalBackground
+type terminalBackgrounds struct { }
+
+// TerminalBackgrounds is the public instance of terminalBackgrounds. Do not reassign this variable
+var TerminalBackgrounds = terminalBackgrounds{}
+
+type TerminalBackground int
+
+/*
+Dark signals to the converter that the output is displayed on a dark background, so dense characters represent bright pixels. This is the default.
+*/
+func (t terminalBackgrounds) Dark() TerminalBackground {
+	return TerminalBackground(0)
+}
+
+/*
+Light signals to the converter that the output is displayed on a light background, so dense characters represent dark pixels.
+*/
+func (t terminalBackgrounds) Light() TerminalBackground {
+	return TerminalBackground(1)
+}
+
+/*
+ParseCOLORFGBG interprets the value of the COLORFGBG environment variable, which some terminals (e.g. rxvt, Konsole, iTerm2) set to "<fg>;<bg>" or "<fg>;<default>;<bg>" using ANSI colour numbers. Background colours 7 (white) and 9-15 (bright colours other than bright black) are treated as light.
+
+The boolean is false if the value could not be interpreted, in which case Dark() is returned.
+*/
+func ParseCOLORFGBG(value string) (TerminalBackground, bool) {
+	fields := strings.Split(value, ";")
+	bg, err := strconv.Atoi(fields[len(fields) - 1])
+	if err != nil || bg < 0 || bg > 15 {
+		return TerminalBackgrounds.Dark(), false
+	}
+
+	if bg == 7 || bg >= 9 {
+		return TerminalBackgrounds.Light(), true
+	}
+
+	return TerminalBackgrounds.Dark(), true
+}
+
+// isLightBackground reports whether bg is TerminalBackgrounds.Light()
+func isLightBackground(bg TerminalBackground) bool {
+	return bg == TerminalBackgrounds.Light()
+}
+
+/*
+invertedLuminosityProvider reports 255 - luminosity for every character. It is handed to the luminosity mapper on light backgrounds, so the character ramp is inverted without the mapper having to know about the background.
+*/
+type invertedLuminosityProvider struct {
+	LuminosityProvider
+}
+
+func (i invertedLuminosityProvider) LuminosityAt(x, y int) int {
+	return 255 - i.LuminosityProvider.LuminosityAt(x, y)
+}
+
+func (i invertedLuminosityProvider) LuminosityAt1D(idx int) int {
+	return 255 - i.LuminosityProvider.LuminosityAt1D(idx)
+}
+
+func (i invertedLuminosityProvider) SafeLuminosityAt(x, y int) int {
+	return 255 - i.LuminosityProvider.SafeLuminosityAt(x, y)
+}
+
+// invertedSobelProvider is the SobelProvider equivalent of invertedLuminosityProvider, handed to the edge mapper on light backgrounds
+type invertedSobelProvider struct {
+	SobelProvider
+}
+
+func (i invertedSobelProvider) LuminosityAt(x, y int) int {
+	return 255 - i.SobelProvider.LuminosityAt(x, y)
+}
+
+func (i invertedSobelProvider) LuminosityAt1D(idx int) int {
+	return 255 - i.SobelProvider.LuminosityAt1D(idx)
+}
+
+func (i invertedSobelProvider) SafeLuminosityAt(x, y int) int {
+	return 255 - i.SobelProvider.SafeLuminosityAt(x, y)
+}
+
+/*
+rampProvider returns the provider the luminosity mapper should see. On a light background the luminosity is inverted, so that dark pixels are given dense characters.
+*/
+func (a *AsciiConverter) rampProvider(lumProv LuminosityProvider) LuminosityProvider {
+	if isLightBackground(a.TerminalBackground) {
+		return invertedLuminosityProvider{lumProv}
+	}
+
+	return lumProv
+}
+
+/*
+rampSobelProvider returns the provider the edge mapper should see. See rampProvider()
+*/
+func (a *AsciiConverter) rampSobelProvider(sobelProv SobelProvider) SobelProvider {
+	if isLightBackground(a.TerminalBackground) {
+		return invertedSobelProvider{sobelProv}
+	}
+
+	return sobelProv
+}
+
+/*
+colorMapper returns the color mapper for a conversion, which is ANSIColorMapper. The library color mappers are rebuilt by WithTerminalBackground(), so it works whether it comes before or after the color mapper option. If TerminalBackground was assigned directly since the library mapper was built (e.g. on a copy of the converter), the library mapper is built for it here instead.
+
+A mapper assigned to ANSIColorMapper directly is always used as is, unless TerminalBackground is also assigned directly afterwards.
+*/
+func (a *AsciiConverter) colorMapper() func(LuminosityProvider, int, int) (int, string) {
+	if a.colorMapperFactory != nil && a.TerminalBackground != a.colorMapperBackground {
+		return a.colorMapperFactory(a.TerminalBackground)
+	}
+
+	return a.ANSIColorMapper
+}
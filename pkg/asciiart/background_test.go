@@ -0,0 +1,259 @@
+package asciiart
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseCOLORFGBG(t *testing.T) {
+	tests := []struct {
+		value	string
+		want	TerminalBackground
+		ok		bool
+	}{
+		{"15;0", TerminalBackgrounds.Dark(), true},
+		{"0;15", TerminalBackgrounds.Light(), true},
+		{"0;7", TerminalBackgrounds.Light(), true},
+		{"7;8", TerminalBackgrounds.Dark(), true},
+		{"0;9", TerminalBackgrounds.Light(), true},
+		{"15;default;0", TerminalBackgrounds.Dark(), true},
+		{"0;default;15", TerminalBackgrounds.Light(), true},
+		{"", TerminalBackgrounds.Dark(), false},
+		{"0;default", TerminalBackgrounds.Dark(), false},
+		{"0;16", TerminalBackgrounds.Dark(), false},
+		{"0;-1", TerminalBackgrounds.Dark(), false},
+		{"white", TerminalBackgrounds.Dark(), false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseCOLORFGBG(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseCOLORFGBG(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// greyLuminosity returns a 1x1 luminosity provider of a grey pixel with luminosity lum
+func greyLuminosity(lum int) LuminosityProvider {
+	return makeTestLuminosity([][]int{{lum}})
+}
+
+func TestColorMapperClamps(t *testing.T) {
+	dark, light := TerminalBackgrounds.Dark(), TerminalBackgrounds.Light()
+
+	tests := []struct {
+		name		string
+		factory		func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string)
+		bg			TerminalBackground
+		// want maps a grey luminosity onto its expected colour code
+		want		map[int]int
+	}{
+		{
+			name: "3 bit dark",
+			factory: func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+				return default3BitColorMapperFactory(default3BitOpts, bg)
+			},
+			bg: dark,
+			// Clamps are measured from black: <= 50 is black, >= 200 is white
+			want: map[int]int{0: 30, 50: 30, 200: 37, 210: 37, 255: 37},
+		},
+		{
+			name: "3 bit light",
+			factory: func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+				return default3BitColorMapperFactory(default3BitOpts, bg)
+			},
+			bg: light,
+			// Clamps are measured from white: >= 205 is white, <= 55 is black
+			want: map[int]int{0: 30, 50: 30, 55: 30, 205: 37, 255: 37},
+		},
+		{
+			name: "4 bit dark",
+			factory: func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+				return default4BitColorMapperFactory(default4BitOpts, bg)
+			},
+			bg: dark,
+			want: map[int]int{0: 30, 45: 90, 200: 37, 250: 97},
+		},
+		{
+			name: "4 bit light",
+			factory: func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+				return default4BitColorMapperFactory(default4BitOpts, bg)
+			},
+			bg: light,
+			// The bright variants are mirrored too: the brightest pixels are bright white, the darkest are black
+			want: map[int]int{255: 97, 210: 37, 55: 90, 5: 30},
+		},
+		{
+			name: "8 bit dark",
+			factory: func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+				return default8BitColorMapperFactory(default8BitOpts, bg)
+			},
+			bg: dark,
+			// Nothing is clamped on a dark background: white is the top of the grey ramp
+			want: map[int]int{0: 16, 16: 16, 240: 255, 255: 255},
+		},
+		{
+			name: "8 bit light",
+			factory: func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+				return default8BitColorMapperFactory(default8BitOpts, bg)
+			},
+			bg: light,
+			// Clamps are measured from white: >= 239 is white, <= 15 is black
+			want: map[int]int{0: 16, 15: 16, 239: 231, 255: 231},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper := tt.factory(tt.bg)
+			for lum, want := range tt.want {
+				if got, _ := mapper(greyLuminosity(lum), 0, 0); got != want {
+					t.Errorf("luminosity %d mapped to %d, want %d", lum, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestColorMapperClampWidthsFollowBackground(t *testing.T) {
+	// The 8 bit white clamp is narrower than the black clamp, so a pixel just outside it on a dark background is inside it on a light one
+	dark := default8BitColorMapperFactory(default8BitOpts, TerminalBackgrounds.Dark())
+	light := default8BitColorMapperFactory(default8BitOpts, TerminalBackgrounds.Light())
+
+	if code, _ := dark(greyLuminosity(239), 0, 0); code == 231 {
+		t.Error("luminosity 239 on dark was clamped to white, want the palette")
+	}
+	if code, _ := light(greyLuminosity(239), 0, 0); code != 231 {
+		t.Errorf("luminosity 239 on light = %d, want white (231)", code)
+	}
+}
+
+func TestTerminalBackgroundOptionOrder(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	for x := range 4 {
+		img.SetGray(x, 0, color.Gray{Y: uint8(x * 80)})
+		img.SetGray(x, 1, color.Gray{Y: uint8(255 - x * 80)})
+	}
+
+	mappers := map[string]AsciiOption{
+		"3 bit": WithDefault3BitColorMapper(),
+		"4 bit": WithDefault4BitColorMapper(),
+		"8 bit": WithDefault8BitColorMapper(),
+	}
+
+	for name, mapperOpt := range mappers {
+		t.Run(name, func(t *testing.T) {
+			before := New(WithTerminalBackground(TerminalBackgrounds.Light()), mapperOpt, WithSobel(false), WithOutputAspectRatio(1))
+			after := New(mapperOpt, WithTerminalBackground(TerminalBackgrounds.Light()), WithSobel(false), WithOutputAspectRatio(1))
+
+			beforeCanvas := before.ConvertToGrid(img, 4, 4)
+			afterCanvas := after.ConvertToGrid(img, 4, 4)
+			for y := range beforeCanvas.Height {
+				for x := range beforeCanvas.Width {
+					if b, a := beforeCanvas.Cells[y][x], afterCanvas.Cells[y][x]; b != a {
+						t.Errorf("cell (%d, %d) = %+v with the background first, %+v with it last", x, y, b, a)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCopiedConverterKeepsItsBackground(t *testing.T) {
+	a := New(WithDefault4BitColorMapper())
+	b := *a
+	b.TerminalBackground = TerminalBackgrounds.Light()
+
+	// Luminosity 210 is white on either background
+	lumProv := greyLuminosity(210)
+	if code, _ := a.colorMapper()(lumProv, 0, 0); code != 37 {
+		t.Errorf("original converter mapped to %d, want 37", code)
+	}
+	if code, _ := b.colorMapper()(lumProv, 0, 0); code != 37 {
+		t.Errorf("copied converter mapped to %d, want 37", code)
+	}
+
+	// Luminosity 230 is white on a dark background, and bright white on a light one. The original must not follow the copy
+	lumProv = greyLuminosity(230)
+	if code, _ := a.colorMapper()(lumProv, 0, 0); code != 37 {
+		t.Errorf("original converter mapped to %d, want 37", code)
+	}
+	if code, _ := b.colorMapper()(lumProv, 0, 0); code != 97 {
+		t.Errorf("copied converter mapped to %d, want 97", code)
+	}
+}
+
+func TestDark8BitColorMapperIsUnchanged(t *testing.T) {
+	// Every combination of channels in steps of 17, so 4096 colours
+	img := image.NewNRGBA(image.Rect(0, 0, 16 * 16 * 16, 1))
+	x := 0
+	for r := 0; r < 256; r += 17 {
+		for g := 0; g < 256; g += 17 {
+			for b := 0; b < 256; b += 17 {
+				img.SetNRGBA(x, 0, color.NRGBA{uint8(r), uint8(g), uint8(b), 255})
+				x++
+			}
+		}
+	}
+
+	lumImg := New().MapLuminosity(img)
+	mappers := map[string]func(LuminosityProvider, int, int) (int, string){
+		"Default8BitColorMapper()": Default8BitColorMapper(),
+		"WithDefault8BitColorMapper()": New(WithDefault8BitColorMapper()).colorMapper(),
+	}
+
+	for name, mapper := range mappers {
+		// The codes of the dark background mapper from before light backgrounds were supported
+		h := fnv.New32a()
+		for x := range img.Bounds().Dx() {
+			code, _ := mapper(lumImg, x, 0)
+			fmt.Fprintf(h, "%d,", code)
+		}
+		if got, want := h.Sum32(), uint32(0x6e2034f8); got != want {
+			t.Errorf("%s codes hash to %#x, want %#x", name, got, want)
+		}
+	}
+}
+
+func TestColorMapperFieldAssignedAfterOption(t *testing.T) {
+	custom := func(LuminosityProvider, int, int) (int, string) {
+		return 1, "\x1b[31m"
+	}
+
+	for _, bg := range []TerminalBackground{TerminalBackgrounds.Dark(), TerminalBackgrounds.Light()} {
+		a := New(WithTerminalBackground(bg), WithDefault8BitColorMapper())
+		a.ANSIColorMapper = custom
+
+		if code, _ := a.colorMapper()(greyLuminosity(0), 0, 0); code != 1 {
+			t.Errorf("background %v: ANSIColorMapper assigned after WithDefault8BitColorMapper() was ignored, got code %d", bg, code)
+		}
+	}
+}
+
+func TestCustomColorMapperIgnoresBackground(t *testing.T) {
+	custom := func(LuminosityProvider, int, int) (int, string) {
+		return 1, "\x1b[31m"
+	}
+
+	a := New(WithDefault4BitColorMapper(), WithColorMapper(custom), WithTerminalBackground(TerminalBackgrounds.Light()))
+	if code, _ := a.colorMapper()(greyLuminosity(0), 0, 0); code != 1 {
+		t.Errorf("custom mapper was replaced, got code %d", code)
+	}
+}
+
+func TestLightBackgroundInvertsRamp(t *testing.T) {
+	lumProv := greyLuminosity(0)
+
+	dark := New().rampProvider(lumProv)
+	light := New(WithTerminalBackground(TerminalBackgrounds.Light())).rampProvider(lumProv)
+
+	if got := DefaultLuminenceMapper(dark, 0, 0); got != ' ' {
+		t.Errorf("black on a dark background = %q, want a space", got)
+	}
+	if got := DefaultLuminenceMapper(light, 0, 0); got != '$' {
+		t.Errorf("black on a light background = %q, want the densest character", got)
+	}
+}
@@ -0,0 +1,297 @@
+package asciiart
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+)
+
+/*
+Cell is a single character of the converted image, before it is rendered to any output format. See Canvas
+*/
+type Cell struct {
+	// Rune is the character drawn in the cell
+	Rune			rune
+	// FG is the colour of the character (the colour of the pixel the cell was sampled from, scaled by alpha). Only meaningful if HasColor() is true
+	FG				color.RGBA
+	// BG is the background colour of the cell (the colour of the pixel, if CellBackgrounds is set). A fully transparent BG (the zero value) means the cell uses the background of the output
+	BG				color.RGBA
+	// ColorCode is the code (or unique identifier) returned by the ANSIColorMapper
+	ColorCode		int
+	// ColorEscape is the formatted ANSI escape sequence returned by the ANSIColorMapper. It is empty if no color mapper is used
+	ColorEscape		string
+	// Bold is set if the character should be drawn in bold (sobel outlines with SobelOutlineIsBold)
+	Bold			bool
+	// IsEdge is set if the character was picked by the edge mapper instead of the luminosity mapper
+	IsEdge			bool
+	// Transparent is set if the cell should be left blank with no styling (see AlphaPolicies.Transparent())
+	Transparent		bool
+}
+
+// HasColor reports whether the cell was coloured by a color mapper
+func (c Cell) HasColor() bool {
+	return c.ColorEscape != ""
+}
+
+/*
+Canvas is the grid of Cells produced by the converter. It is independent of any output format: a Renderer turns it into ANSI text, HTML etc.
+
+Cells is indexed [y][x].
+*/
+type Canvas struct {
+	Width			int
+	Height			int
+	Cells			[][]Cell
+	// AspectRatio is the effective aspect ratio (cell height / cell width) the canvas was sampled with. See DownscaleImage()
+	AspectRatio		float64
+	// EdgesDetected is set if the canvas was generated with sobel edge detection
+	EdgesDetected	bool
+	// Settings records the converter settings the canvas was generated with
+	Settings		CanvasSettings
+}
+
+/*
+CanvasSettings records the settings of the converter that generated a Canvas, so that serialised canvases (see JSONRenderer() and BinaryRenderer()) describe how they were made.
+
+Luminosity filters and pre-filters are functions, so only the length of each chain is recorded.
+*/
+type CanvasSettings struct {
+	OutputAspectRatio					float64				`json:"outputAspectRatio"`
+	Brightness							float64				`json:"brightness"`
+	Contrast							float64				`json:"contrast"`
+	Gamma								float64				`json:"gamma"`
+	LinearLight							bool				`json:"linearLight"`
+	TerminalBackground					TerminalBackground	`json:"terminalBackground"`
+	LuminanceModel						LuminanceModel		`json:"luminanceModel"`
+	AlphaPolicy							AlphaPolicy			`json:"alphaPolicy"`
+	UseSobel							bool				`json:"useSobel"`
+	SobelMagnitudeSqThresholdNormalized	float64				`json:"sobelMagnitudeSqThreshold"`
+	SobelLaplacianThresholdNormalized	float64				`json:"sobelLaplacianThreshold"`
+	EdgeColorMode						EdgeColorMode		`json:"edgeColorMode"`
+	// LuminosityFilterCount is the number of LuminosityFilters applied
+	LuminosityFilterCount				int					`json:"luminosityFilters"`
+	// PreFilterCount is the number of PreFilters applied
+	PreFilterCount						int					`json:"preFilters"`
+}
+
+// makeCanvas allocates a canvas of width x height cells
+func (a *AsciiConverter) makeCanvas(width, height int, aspect_ratio float64) *Canvas {
+	canvas := a.canvasHeader(width, height, aspect_ratio)
+	canvas.Cells = makeCells(width, height)
+	return canvas
+}
+
+// makeCells allocates the rows of a canvas of width x height cells
+func makeCells(width, height int) [][]Cell {
+	cells := make([]Cell, width * height)
+	rows := make([][]Cell, height)
+	for y := range height {
+		rows[y] = cells[y * width : (y + 1) * width]
+	}
+	return rows
+}
+
+// canvasHeader returns a canvas of width x height cells without allocating the cells
+func (a *AsciiConverter) canvasHeader(width, height int, aspect_ratio float64) *Canvas {
+	return &Canvas {
+		Width: width,
+		Height: height,
+		AspectRatio: aspect_ratio,
+		Settings: CanvasSettings {
+			OutputAspectRatio: a.OutputAspectRatio,
+			Brightness: a.Brightness,
+			Contrast: a.Contrast,
+			Gamma: a.Gamma,
+			LinearLight: a.LinearLight,
+			TerminalBackground: a.TerminalBackground,
+			LuminanceModel: a.LuminanceModel,
+			AlphaPolicy: a.AlphaPolicy,
+			UseSobel: a.UseSobel,
+			SobelMagnitudeSqThresholdNormalized: a.SobelMagnitudeSqThresholdNormalized,
+			SobelLaplacianThresholdNormalized: a.SobelLaplacianThresholdNormalized,
+			EdgeColorMode: a.EdgeColorMode,
+			LuminosityFilterCount: len(a.LuminosityFilters),
+			PreFilterCount: len(a.PreFilters),
+		},
+	}
+}
+
+// At returns the cell at x, y
+func (c *Canvas) At(x, y int) Cell {
+	return c.Cells[y][x]
+}
+
+// colorCell fills in the colour of cell from the pixel at x, y, using colorMapper for the escape sequence. The background is filled in too if withBG is set (see CellBackgrounds)
+func colorCell(cell *Cell, colorMapper func(LuminosityProvider, int, int) (int, string), withBG bool, lumProv LuminosityProvider, x, y int) {
+	r8, g8, b8 := channelSplit(lumProv.At(x, y))
+	cell.FG = color.RGBA{uint8(r8), uint8(g8), uint8(b8), 255}
+	cell.ColorCode, cell.ColorEscape = colorMapper(lumProv, x, y)
+
+	if withBG {
+		cell.BG = cell.FG
+	}
+}
+
+/*
+CellGenWithSobel converts a SobelProvider to a Canvas. If you are not interested in making custom generators, see ConvertToGrid()
+*/
+func (a *AsciiConverter) CellGenWithSobel(sobelProv SobelProvider, aspect_ratio float64) *Canvas {
+	// context.Background() is never cancelled, so there is no error
+	canvas, _ := a.cellGenWithSobel(context.Background(), sobelProv, aspect_ratio)
+	return canvas
+}
+
+// cellGenWithSobel is CellGenWithSobel(), stopping with ctx.Err() if ctx is cancelled
+func (a *AsciiConverter) cellGenWithSobel(ctx context.Context, sobelProv SobelProvider, aspect_ratio float64) (*Canvas, error) {
+	canvas := a.makeCanvas(sobelProv.Width(), sobelProv.Height(), aspect_ratio)
+	canvas.EdgesDetected = true
+
+	if err := a.parallelRows(ctx, canvas.Height, a.sobelRowGen(sobelProv, aspect_ratio).into(canvas)); err != nil {
+		return nil, err
+	}
+
+	return canvas, nil
+}
+
+/*
+rowGen fills in the cells of row y of a canvas. The row must be zeroed first. It is safe to call for different rows at once.
+*/
+type rowGen func(row []Cell, y int)
+
+// into returns a function that fills in row y of canvas, for parallelRows()
+func (gen rowGen) into(canvas *Canvas) func(y int) {
+	return func(y int) {
+		gen(canvas.Cells[y], y)
+	}
+}
+
+// sobelRowGen returns the rowGen of cellGenWithSobel()
+func (a *AsciiConverter) sobelRowGen(sobelProv SobelProvider, aspect_ratio float64) rowGen {
+	adjustedGMag2Threshold := int(a.SobelMagnitudeSqThresholdNormalized * (aspect_ratio * aspect_ratio))
+
+	edgeMapper := a.EdgeMapperFactory(aspect_ratio)
+	rampProv := a.rampSobelProvider(sobelProv)
+	colorMapper := a.colorMapper()
+
+	return func(row []Cell, y int) {
+		for x := range row {
+			cell := &row[x]
+
+			if a.isTransparentCell(sobelProv, x, y) {
+				cell.Rune = ' '
+				cell.Transparent = true
+				continue
+			}
+
+			colorCell(cell, colorMapper, a.CellBackgrounds, sobelProv, x, y)
+
+			// Check if we should use the edge or the luminosity mapper
+			if sobelProv.SobelMag2At(x, y) >= adjustedGMag2Threshold &&
+				math.Abs(sobelProv.SobelLaplacianAt(x, y)) <= a.SobelLaplacianThresholdNormalized {
+
+				cell.IsEdge = true
+				cell.Bold = a.SobelOutlineIsBold
+				cell.Rune = edgeMapper(rampProv, x, y)
+			} else {
+				cell.Rune = a.LuminosityMapper(rampProv, x, y)
+			}
+		}
+	}
+}
+
+/*
+CellGen converts a LuminosityProvider to a Canvas. If you are not interested in making custom generators, see ConvertToGrid()
+*/
+func (a *AsciiConverter) CellGen(lumProv LuminosityProvider, aspect_ratio float64) *Canvas {
+	// context.Background() is never cancelled, so there is no error
+	canvas, _ := a.cellGen(context.Background(), lumProv, aspect_ratio)
+	return canvas
+}
+
+// cellGen is CellGen(), stopping with ctx.Err() if ctx is cancelled
+func (a *AsciiConverter) cellGen(ctx context.Context, lumProv LuminosityProvider, aspect_ratio float64) (*Canvas, error) {
+	canvas := a.makeCanvas(lumProv.Width(), lumProv.Height(), aspect_ratio)
+
+	if err := a.parallelRows(ctx, canvas.Height, a.lumRowGen(lumProv).into(canvas)); err != nil {
+		return nil, err
+	}
+
+	return canvas, nil
+}
+
+// lumRowGen returns the rowGen of cellGen()
+func (a *AsciiConverter) lumRowGen(lumProv LuminosityProvider) rowGen {
+	rampProv := a.rampProvider(lumProv)
+	colorMapper := a.colorMapper()
+
+	return func(row []Cell, y int) {
+		for x := range row {
+			cell := &row[x]
+
+			if a.isTransparentCell(lumProv, x, y) {
+				cell.Rune = ' '
+				cell.Transparent = true
+				continue
+			}
+
+			colorCell(cell, colorMapper, a.CellBackgrounds, lumProv, x, y)
+			cell.Rune = a.LuminosityMapper(rampProv, x, y)
+		}
+	}
+}
+
+/*
+ConvertToGrid runs the conversion pipeline on img and returns the resulting Canvas, without rendering it to any output format. See Convert() for the parameters, and Renderer for turning the Canvas into text.
+*/
+func (a *AsciiConverter) ConvertToGrid(img image.Image, targetWidth, targetHeight int) *Canvas {
+	// context.Background() is never cancelled, so there is no error
+	canvas, _ := a.convertToGrid(context.Background(), img, targetWidth, targetHeight)
+	return canvas
+}
+
+/*
+convertToGrid is ConvertToGrid(), stopping with ctx.Err() if ctx is cancelled. Cancellation is checked between the stages of the pipeline, and between the rows (or columns) of every stage.
+*/
+func (a *AsciiConverter) convertToGrid(ctx context.Context, img image.Image, targetWidth, targetHeight int) (*Canvas, error) {
+	canvas, gen, err := a.convertToRows(ctx, img, targetWidth, targetHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	canvas.Cells = makeCells(canvas.Width, canvas.Height)
+	if err := a.parallelRows(ctx, canvas.Height, gen.into(canvas)); err != nil {
+		return nil, err
+	}
+
+	return canvas, nil
+}
+
+/*
+convertToRows runs every stage of the pipeline on img except generating the cells. It returns the canvas without its cells, and the rowGen that generates its rows, so they can be generated one at a time (see ConvertTo()). See convertToGrid() for ctx.
+*/
+func (a *AsciiConverter) convertToRows(ctx context.Context, img image.Image, targetWidth, targetHeight int) (*Canvas, rowGen, error) {
+	src := img
+	img, effectiveAspectRatio, err := a.downscaleImage(ctx, img, targetWidth, targetHeight)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lumImg, err := a.mapLuminosity(ctx, img)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if a.UseSobel {
+		sobelImg, err := a.applySobelSupersampled(ctx, src, lumImg, effectiveAspectRatio)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		canvas := a.canvasHeader(sobelImg.Width(), sobelImg.Height(), effectiveAspectRatio)
+		canvas.EdgesDetected = true
+		return canvas, a.sobelRowGen(sobelImg, effectiveAspectRatio), nil
+	}
+
+	return a.canvasHeader(lumImg.Width(), lumImg.Height(), effectiveAspectRatio), a.lumRowGen(lumImg), nil
+}
@@ -0,0 +1,314 @@
+package asciiart
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// canvasBinaryMagic identifies the binary canvas format
+const canvasBinaryMagic = "AACV"
+
+// Flags of the binary canvas format, on top of the cell flags shared with the JSON format
+const (
+	binaryCellFlagBG		= 1 << (iota + 4)
+	binaryCellFlagColor
+)
+
+/*
+BinaryRenderer returns a Renderer that serialises the canvas to a compact binary format, decoded with DecodeCanvasBinary(). It holds the same information as JSONRenderer(), at a fraction of the size. All integers are little endian, and varints use encoding/binary:
+
+	"AACV"								magic
+	uint8								version (1)
+	uvarint width, uvarint height			a canvas without columns has no rows
+	float64 AspectRatio
+	uint8								1 if EdgesDetected
+	float64 OutputAspectRatio, float64 Brightness, float64 Contrast, float64 Gamma
+	uint8 LinearLight, uint8 TerminalBackground
+	uvarint r, g, b luminance weights		out of 10000, all 0 is Rec. 709
+	uint8 luminance max channel, uint8 AlphaPolicy, uint8 UseSobel
+	float64 SobelMagnitudeSqThresholdNormalized, float64 SobelLaplacianThresholdNormalized
+	uint8 EdgeColorMode
+	uvarint LuminosityFilterCount, uvarint PreFilterCount
+	uvarint palette length, then for each entry: varint code, uvarint length, escape bytes
+	width * height cells, row by row:
+		uvarint rune
+		uint8 flags							1 = bold, 2 = edge, 4 = transparent, 16 = has background, 32 = coloured
+		3 bytes r, g, b of FG				unless transparent
+		4 bytes r, g, b, a of BG			if flag 16
+		uvarint palette index				if flag 32
+*/
+func BinaryRenderer() Renderer {
+	return func(w io.Writer, canvas *Canvas) error {
+		bw := bufio.NewWriter(w)
+		var buf [binary.MaxVarintLen64]byte
+
+		putUvarint := func(v uint64) {
+			bw.Write(buf[:binary.PutUvarint(buf[:], v)])
+		}
+		putFloat := func(f float64) {
+			binary.Write(bw, binary.LittleEndian, math.Float64bits(f))
+		}
+		putBool := func(b bool) {
+			if b {
+				bw.WriteByte(1)
+			} else {
+				bw.WriteByte(0)
+			}
+		}
+
+		bw.WriteString(canvasBinaryMagic)
+		bw.WriteByte(canvasFormatVersion)
+		putUvarint(uint64(canvas.Width))
+		putUvarint(uint64(canvas.Height))
+		putFloat(canvas.AspectRatio)
+		putBool(canvas.EdgesDetected)
+
+		settings := canvas.Settings
+		putFloat(settings.OutputAspectRatio)
+		putFloat(settings.Brightness)
+		putFloat(settings.Contrast)
+		putFloat(settings.Gamma)
+		putBool(settings.LinearLight)
+		bw.WriteByte(byte(settings.TerminalBackground))
+		for _, w := range settings.LuminanceModel.weights {
+			putUvarint(uint64(w))
+		}
+		putBool(settings.LuminanceModel.maxChannel)
+		bw.WriteByte(byte(settings.AlphaPolicy))
+		putBool(settings.UseSobel)
+		putFloat(settings.SobelMagnitudeSqThresholdNormalized)
+		putFloat(settings.SobelLaplacianThresholdNormalized)
+		bw.WriteByte(byte(settings.EdgeColorMode))
+		putUvarint(uint64(settings.LuminosityFilterCount))
+		putUvarint(uint64(settings.PreFilterCount))
+
+		// Collect the palette first, since it is written before the cells
+		paletteIdx := make(map[jsonPaletteEntry]int)
+		var palette []jsonPaletteEntry
+		for _, row := range canvas.Cells {
+			for _, cell := range row {
+				if !cell.HasColor() {
+					continue
+				}
+				entry := jsonPaletteEntry{Code: cell.ColorCode, Escape: cell.ColorEscape}
+				if _, ok := paletteIdx[entry]; !ok {
+					paletteIdx[entry] = len(palette)
+					palette = append(palette, entry)
+				}
+			}
+		}
+
+		putUvarint(uint64(len(palette)))
+		for _, entry := range palette {
+			bw.Write(buf[:binary.PutVarint(buf[:], int64(entry.Code))])
+			putUvarint(uint64(len(entry.Escape)))
+			bw.WriteString(entry.Escape)
+		}
+
+		for _, row := range canvas.Cells {
+			for _, cell := range row {
+				putUvarint(uint64(cell.Rune))
+
+				flags := cellFlags(cell)
+				if cell.BG.A != 0 {
+					flags |= binaryCellFlagBG
+				}
+				if cell.HasColor() {
+					flags |= binaryCellFlagColor
+				}
+				bw.WriteByte(byte(flags))
+
+				if !cell.Transparent {
+					bw.Write([]byte{cell.FG.R, cell.FG.G, cell.FG.B})
+				}
+				if flags & binaryCellFlagBG != 0 {
+					bw.Write([]byte{cell.BG.R, cell.BG.G, cell.BG.B, cell.BG.A})
+				}
+				if flags & binaryCellFlagColor != 0 {
+					putUvarint(uint64(paletteIdx[jsonPaletteEntry{Code: cell.ColorCode, Escape: cell.ColorEscape}]))
+				}
+			}
+		}
+
+		return bw.Flush()
+	}
+}
+
+/*
+DecodeCanvasBinary decodes a canvas written by BinaryRenderer(). The decoded canvas can be rendered with any Renderer.
+*/
+func DecodeCanvasBinary(r io.Reader) (*Canvas, error) {
+	br := bufio.NewReader(r)
+
+	// Any error while reading is reported once at the end, so the reads below stay readable
+	var readErr error
+	readByte := func() byte {
+		if readErr != nil {
+			return 0
+		}
+		b, err := br.ReadByte()
+		readErr = err
+		return b
+	}
+	readUvarint := func() uint64 {
+		if readErr != nil {
+			return 0
+		}
+		v, err := binary.ReadUvarint(br)
+		readErr = err
+		return v
+	}
+	readFloat := func() float64 {
+		if readErr != nil {
+			return 0
+		}
+		var bits uint64
+		readErr = binary.Read(br, binary.LittleEndian, &bits)
+		return math.Float64frombits(bits)
+	}
+	readBytes := func(n int) []byte {
+		b := make([]byte, n)
+		if readErr != nil {
+			return b
+		}
+		_, readErr = io.ReadFull(br, b)
+		return b
+	}
+
+	if magic := readBytes(len(canvasBinaryMagic)); readErr == nil && string(magic) != canvasBinaryMagic {
+		return nil, errors.New("Not a binary canvas")
+	}
+	version := readByte()
+	if readErr == nil && version != canvasFormatVersion {
+		return nil, fmt.Errorf("Unsupported canvas version: %d", version)
+	}
+
+	width, height := readUvarint(), readUvarint()
+	if readErr == nil && (width > maxCanvasCells || height > maxCanvasCells) {
+		return nil, fmt.Errorf("Canvas is too big: %dx%d", width, height)
+	}
+	if err := checkCanvasSize(int(width), int(height)); readErr == nil && err != nil {
+		return nil, err
+	}
+	// Rows without columns take no bytes to encode, so a few bytes could claim millions of them
+	if readErr == nil && width == 0 && height != 0 {
+		return nil, fmt.Errorf("Invalid canvas size: %dx%d", width, height)
+	}
+
+	canvas := &Canvas {
+		Width: int(width),
+		Height: int(height),
+		AspectRatio: readFloat(),
+		EdgesDetected: readByte() != 0,
+	}
+
+	canvas.Settings.OutputAspectRatio = readFloat()
+	canvas.Settings.Brightness = readFloat()
+	canvas.Settings.Contrast = readFloat()
+	canvas.Settings.Gamma = readFloat()
+	canvas.Settings.LinearLight = readByte() != 0
+	canvas.Settings.TerminalBackground = TerminalBackground(readByte())
+
+	var weights [3]int
+	for i := range weights {
+		// Anything above the scale is rejected by makeLuminanceModel(), so clamp before converting to int
+		weights[i] = int(min(readUvarint(), luminanceWeightScale + 1))
+	}
+	maxChannel := readByte() != 0
+
+	model, err := makeLuminanceModel(weights, maxChannel)
+	if readErr == nil && err != nil {
+		return nil, err
+	}
+
+	canvas.Settings.LuminanceModel = model
+	canvas.Settings.AlphaPolicy = AlphaPolicy(readByte())
+	canvas.Settings.UseSobel = readByte() != 0
+	canvas.Settings.SobelMagnitudeSqThresholdNormalized = readFloat()
+	canvas.Settings.SobelLaplacianThresholdNormalized = readFloat()
+	canvas.Settings.EdgeColorMode = EdgeColorMode(readByte())
+
+	lumFilters, preFilters := readUvarint(), readUvarint()
+	if readErr == nil && (lumFilters > maxCanvasCells || preFilters > maxCanvasCells) {
+		return nil, fmt.Errorf("Invalid filter counts: %d, %d", lumFilters, preFilters)
+	}
+	canvas.Settings.LuminosityFilterCount = int(lumFilters)
+	canvas.Settings.PreFilterCount = int(preFilters)
+
+	paletteLen := readUvarint()
+	if readErr == nil && paletteLen > maxCanvasCells {
+		return nil, fmt.Errorf("Palette is too big: %d", paletteLen)
+	}
+
+	var palette []jsonPaletteEntry
+	for range paletteLen {
+		if readErr != nil {
+			break
+		}
+
+		code, err := binary.ReadVarint(br)
+		if err != nil {
+			readErr = err
+			break
+		}
+
+		escapeLen := readUvarint()
+		if readErr == nil && escapeLen > math.MaxUint16 {
+			return nil, fmt.Errorf("Palette entry is too long: %d", escapeLen)
+		}
+		palette = append(palette, jsonPaletteEntry{Code: int(code), Escape: string(readBytes(int(escapeLen)))})
+	}
+
+	// Cells are appended as they are read instead of allocated from the size in the header, so a few bytes of input claiming a huge canvas cannot force a huge allocation
+	n := canvas.Width * canvas.Height
+	cells := make([]Cell, 0, min(n, 1 << 12))
+	for range n {
+		if readErr != nil {
+			break
+		}
+
+		cells = append(cells, Cell{})
+		cell := &cells[len(cells) - 1]
+		cell.Rune = rune(readUvarint())
+
+		flags := int(readByte())
+		applyCellFlags(cell, flags)
+
+		if !cell.Transparent {
+			fg := readBytes(3)
+			cell.FG.R, cell.FG.G, cell.FG.B, cell.FG.A = fg[0], fg[1], fg[2], 255
+		}
+		if flags & binaryCellFlagBG != 0 {
+			bg := readBytes(4)
+			cell.BG.R, cell.BG.G, cell.BG.B, cell.BG.A = bg[0], bg[1], bg[2], bg[3]
+		}
+		if flags & binaryCellFlagColor != 0 {
+			idx := readUvarint()
+			if readErr == nil && idx >= uint64(len(palette)) {
+				return nil, fmt.Errorf("Colour index %d is out of range of the palette", idx)
+			}
+			if readErr == nil {
+				cell.ColorCode = palette[idx].Code
+				cell.ColorEscape = palette[idx].Escape
+			}
+		}
+	}
+
+	if readErr != nil {
+		if readErr == io.EOF {
+			readErr = io.ErrUnexpectedEOF
+		}
+		return nil, readErr
+	}
+
+	canvas.Cells = make([][]Cell, canvas.Height)
+	for y := range canvas.Height {
+		canvas.Cells[y] = cells[y * canvas.Width : (y + 1) * canvas.Width]
+	}
+
+	return canvas, nil
+}
@@ -0,0 +1,138 @@
+package asciiart
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"io"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// encodeBinary encodes canvas with BinaryRenderer(), failing the test on error
+func encodeBinary(t *testing.T, canvas *Canvas) []byte {
+	t.Helper()
+	return []byte(renderToString(t, BinaryRenderer(), canvas))
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	// Unlike JSON, the binary format keeps the alpha of backgrounds
+	translucent := roundTripCanvas()
+	translucent.Cells[0][0].BG = color.RGBA{10, 20, 30, 40}
+
+	canvases := map[string]*Canvas {
+		"every field": roundTripCanvas(),
+		"converted": convertedCanvas(),
+		"translucent background": translucent,
+		"empty": &Canvas{Cells: [][]Cell{}, AspectRatio: 1},
+	}
+
+	for name, canvas := range canvases {
+		t.Run(name, func(t *testing.T) {
+			decoded, err := DecodeCanvasBinary(bytes.NewReader(encodeBinary(t, canvas)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, canvas) {
+				t.Errorf("decoded canvas differs\ngot  %+v\nwant %+v", decoded, canvas)
+			}
+		})
+	}
+}
+
+func TestBinaryMatchesJSON(t *testing.T) {
+	canvas := convertedCanvas()
+
+	fromJSON, err := DecodeCanvasJSON(bytes.NewReader([]byte(renderToString(t, JSONRenderer(), canvas))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBinary, err := DecodeCanvasBinary(bytes.NewReader(encodeBinary(t, canvas)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(fromJSON, fromBinary) {
+		t.Errorf("JSON and binary decode differently\njson   %+v\nbinary %+v", fromJSON, fromBinary)
+	}
+}
+
+func TestDecodeCanvasBinaryRejectsBadInput(t *testing.T) {
+	valid := encodeBinary(t, roundTripCanvas())
+
+	// Every truncation of a valid canvas is an error, never a panic
+	for n := range len(valid) {
+		if _, err := DecodeCanvasBinary(bytes.NewReader(valid[:n])); err == nil {
+			t.Errorf("canvas truncated to %d of %d bytes was decoded", n, len(valid))
+		}
+	}
+
+	header := func(version byte, width, height uint64) []byte {
+		b := append([]byte(canvasBinaryMagic), version)
+		b = binary.AppendUvarint(b, width)
+		return binary.AppendUvarint(b, height)
+	}
+
+	// The last cell is coloured, so its palette index is the last byte
+	badIndex := encodeBinary(t, testCanvas([]Cell{{Rune: 'a', ColorCode: 31, ColorEscape: "\x1b[31m"}}))
+	badIndex[len(badIndex) - 1] = 5
+
+	badWeights := roundTripCanvas()
+	badWeights.Settings.LuminanceModel = LuminanceModel{weights: [3]int{1, 2, 3}}
+
+	tests := []struct {
+		name	string
+		input	[]byte
+	}{
+		{"wrong magic", append([]byte("AAXX"), valid[4:]...)},
+		{"version 0", header(0, 1, 1)},
+		{"future version", header(canvasFormatVersion + 1, 1, 1)},
+		{"too many cells", header(canvasFormatVersion, 4096, 4096)},
+		{"product overflows", header(canvasFormatVersion, 1 << 62, 4)},
+		{"too tall", header(canvasFormatVersion, 1, maxCanvasCells + 1)},
+		{"rows without columns", header(canvasFormatVersion, 0, 1 << 20)},
+		{"palette index out of range", badIndex},
+		{"invalid luminance weights", encodeBinary(t, badWeights)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if canvas, err := DecodeCanvasBinary(bytes.NewReader(tt.input)); err == nil {
+				t.Errorf("decoded %+v, want an error", canvas)
+			}
+		})
+	}
+}
+
+func TestDecodeCanvasBinaryAllocatesForTheCellsRead(t *testing.T) {
+	// A header claiming the biggest canvas allowed, followed by the rest of a valid header and no cells
+	valid := encodeBinary(t, testCanvas([]Cell{{Rune: 'a'}}))
+	input := append([]byte(canvasBinaryMagic), canvasFormatVersion)
+	input = binary.AppendUvarint(input, 2048)
+	input = binary.AppendUvarint(input, 2048)
+	// The 1x1 header has single byte sizes, so everything after them is the rest of the header, then the one cell (2 bytes for the rune and flags, 3 for FG)
+	input = append(input, valid[len(canvasBinaryMagic) + 3 : len(valid) - 5]...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := DecodeCanvasBinary(bytes.NewReader(input))
+	runtime.ReadMemStats(&after)
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// Allocating every cell up front would take over 200 MB
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1 << 20 {
+		t.Errorf("decoding a truncated canvas allocated %d bytes, want under 1 MB", allocated)
+	}
+}
+
+func TestBinaryIsSmallerThanJSON(t *testing.T) {
+	canvas := convertedCanvas()
+	if b, j := len(encodeBinary(t, canvas)), len(renderToString(t, JSONRenderer(), canvas)); b >= j {
+		t.Errorf("binary canvas is %d bytes, JSON %d", b, j)
+	}
+}
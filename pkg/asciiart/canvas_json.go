@@ -0,0 +1,303 @@
+package asciiart
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// canvasFormatVersion is the version of the JSON and binary canvas formats
+const canvasFormatVersion = 1
+
+// Maximum width, height and number of cells accepted by the canvas decoders, so corrupt input cannot allocate unbounded memory
+const maxCanvasCells = 1 << 22
+
+// Cell flags shared by the JSON and binary canvas formats
+const (
+	cellFlagBold		= 1 << iota
+	cellFlagEdge
+	cellFlagTransparent
+)
+
+// jsonPaletteEntry is a colour returned by the ANSIColorMapper
+type jsonPaletteEntry struct {
+	Code		int		`json:"code"`
+	Escape		string	`json:"escape"`
+}
+
+// jsonCanvas is the JSON representation of a Canvas. See JSONRenderer() for the schema
+type jsonCanvas struct {
+	Version			int					`json:"version"`
+	Width			int					`json:"width"`
+	Height			int					`json:"height"`
+	AspectRatio		float64				`json:"aspectRatio"`
+	EdgesDetected	bool				`json:"edgesDetected"`
+	Settings		CanvasSettings		`json:"settings"`
+	Glyphs			[]string			`json:"glyphs"`
+	FG				[]string			`json:"fg"`
+	BG				[]string			`json:"bg"`
+	Flags			[]int				`json:"flags"`
+	Palette			[]jsonPaletteEntry	`json:"palette"`
+	Colors			[]int				`json:"colors"`
+}
+
+func formatJSONColor(c color.RGBA) string {
+	return "#" + hexColor(c)
+}
+
+// jsonLuminanceModel is the JSON representation of a LuminanceModel
+type jsonLuminanceModel struct {
+	Weights		[3]int		`json:"weights"`
+	MaxChannel	bool		`json:"maxChannel"`
+}
+
+// MarshalJSON encodes the model as its fixed point weights (see JSONRenderer())
+func (l LuminanceModel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonLuminanceModel{Weights: l.weights, MaxChannel: l.maxChannel})
+}
+
+// UnmarshalJSON decodes a model encoded by MarshalJSON()
+func (l *LuminanceModel) UnmarshalJSON(b []byte) error {
+	var jl jsonLuminanceModel
+	if err := json.Unmarshal(b, &jl); err != nil {
+		return err
+	}
+
+	model, err := makeLuminanceModel(jl.Weights, jl.MaxChannel)
+	if err != nil {
+		return err
+	}
+
+	*l = model
+	return nil
+}
+
+// makeLuminanceModel validates the fixed point weights of a decoded LuminanceModel. They must be all zero (the zero value), or sum to luminanceWeightScale
+func makeLuminanceModel(weights [3]int, maxChannel bool) (LuminanceModel, error) {
+	sum := 0
+	for _, w := range weights {
+		if w < 0 {
+			return LuminanceModel{}, fmt.Errorf("Invalid luminance weights: %v", weights)
+		}
+		sum += w
+	}
+
+	if sum != 0 && sum != luminanceWeightScale {
+		return LuminanceModel{}, fmt.Errorf("Invalid luminance weights: %v, expected them to sum to %d", weights, luminanceWeightScale)
+	}
+
+	return LuminanceModel{weights: weights, maxChannel: maxChannel}, nil
+}
+
+// checkCanvasSize returns an error if a decoded canvas of width x height is negative or too big to allocate
+func checkCanvasSize(width, height int) error {
+	if width < 0 || height < 0 {
+		return fmt.Errorf("Invalid canvas size: %dx%d", width, height)
+	}
+
+	// Both are bounded first, so the product cannot overflow
+	if width > maxCanvasCells || height > maxCanvasCells || width * height > maxCanvasCells {
+		return fmt.Errorf("Canvas is too big: %dx%d", width, height)
+	}
+
+	return nil
+}
+
+func parseJSONColor(s string) (color.RGBA, error) {
+	var c color.RGBA
+	if s == "" {
+		return c, nil
+	}
+
+	if _, err := fmt.Sscanf(s, "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil || len(s) != 7 {
+		return c, fmt.Errorf("Invalid colour %q, expected #rrggbb", s)
+	}
+
+	c.A = 255
+	return c, nil
+}
+
+/*
+JSONRenderer returns a Renderer that serialises the canvas to JSON, so it can be rendered by another program or decoded again with DecodeCanvasJSON(). The schema (version 1) is:
+
+	{
+		"version": 1,
+		"width": <int>,
+		"height": <int>,
+		"aspectRatio": <float>,				// Canvas.AspectRatio
+		"edgesDetected": <bool>,			// Canvas.EdgesDetected
+		"settings": {						// Canvas.Settings
+			"outputAspectRatio": <float>,
+			"brightness": <float>,
+			"contrast": <float>,
+			"gamma": <float>,
+			"linearLight": <bool>,
+			"terminalBackground": <int>,	// 0 = dark, 1 = light
+			"luminanceModel": {
+				"weights": [<int>, <int>, <int>],	// r, g, b weights out of 10000. All 0 is Rec. 709
+				"maxChannel": <bool>
+			},
+			"alphaPolicy": <int>,			// 0 = multiply, 1 = composite, 2 = transparent
+			"useSobel": <bool>,
+			"sobelMagnitudeSqThreshold": <float>,
+			"sobelLaplacianThreshold": <float>,
+			"edgeColorMode": <int>,			// 0 = luminosity, 1 = rgb, 2 = lab
+			"luminosityFilters": <int>,		// Number of luminosity filters applied
+			"preFilters": <int>				// Number of pre-filters applied
+		},
+		"glyphs": [<string>, ...],			// One string per row, of exactly width characters
+		"fg": [<string>, ...],				// width * height colours ("#rrggbb", or "" for transparent cells), row by row
+		"bg": [<string>, ...],				// width * height background colours ("#rrggbb", or "" for none), row by row
+		"flags": [<int>, ...],				// width * height bit flags, row by row: 1 = bold, 2 = edge, 4 = transparent
+		"palette": [{"code": <int>, "escape": <string>}, ...],	// Distinct results of the ANSIColorMapper
+		"colors": [<int>, ...]				// width * height indexes into palette, or -1 if the cell was not coloured, row by row
+	}
+*/
+func JSONRenderer() Renderer {
+	return func(w io.Writer, canvas *Canvas) error {
+		n := canvas.Width * canvas.Height
+		jc := jsonCanvas {
+			Version: canvasFormatVersion,
+			Width: canvas.Width,
+			Height: canvas.Height,
+			AspectRatio: canvas.AspectRatio,
+			EdgesDetected: canvas.EdgesDetected,
+			Settings: canvas.Settings,
+			Glyphs: make([]string, 0, canvas.Height),
+			FG: make([]string, 0, n),
+			BG: make([]string, 0, n),
+			Flags: make([]int, 0, n),
+			Palette: []jsonPaletteEntry{},
+			Colors: make([]int, 0, n),
+		}
+
+		paletteIdx := make(map[jsonPaletteEntry]int)
+
+		for _, row := range canvas.Cells {
+			glyphs := make([]rune, len(row))
+
+			for x, cell := range row {
+				glyphs[x] = cell.Rune
+
+				fg, bg := "", ""
+				if !cell.Transparent {
+					fg = formatJSONColor(cell.FG)
+				}
+				if cell.BG.A != 0 {
+					bg = formatJSONColor(cell.BG)
+				}
+				jc.FG = append(jc.FG, fg)
+				jc.BG = append(jc.BG, bg)
+				jc.Flags = append(jc.Flags, cellFlags(cell))
+
+				colorIdx := -1
+				if cell.HasColor() {
+					entry := jsonPaletteEntry{Code: cell.ColorCode, Escape: cell.ColorEscape}
+					idx, ok := paletteIdx[entry]
+					if !ok {
+						idx = len(jc.Palette)
+						paletteIdx[entry] = idx
+						jc.Palette = append(jc.Palette, entry)
+					}
+					colorIdx = idx
+				}
+				jc.Colors = append(jc.Colors, colorIdx)
+			}
+
+			jc.Glyphs = append(jc.Glyphs, string(glyphs))
+		}
+
+		return json.NewEncoder(w).Encode(&jc)
+	}
+}
+
+// cellFlags packs the boolean fields of cell into bit flags
+func cellFlags(cell Cell) int {
+	flags := 0
+	if cell.Bold {
+		flags |= cellFlagBold
+	}
+	if cell.IsEdge {
+		flags |= cellFlagEdge
+	}
+	if cell.Transparent {
+		flags |= cellFlagTransparent
+	}
+	return flags
+}
+
+// applyCellFlags unpacks flags (see cellFlags()) into cell
+func applyCellFlags(cell *Cell, flags int) {
+	cell.Bold = flags & cellFlagBold != 0
+	cell.IsEdge = flags & cellFlagEdge != 0
+	cell.Transparent = flags & cellFlagTransparent != 0
+}
+
+/*
+DecodeCanvasJSON decodes a canvas written by JSONRenderer(). The decoded canvas can be rendered with any Renderer.
+*/
+func DecodeCanvasJSON(r io.Reader) (*Canvas, error) {
+	var jc jsonCanvas
+	if err := json.NewDecoder(r).Decode(&jc); err != nil {
+		return nil, err
+	}
+
+	if jc.Version != canvasFormatVersion {
+		return nil, fmt.Errorf("Unsupported canvas version: %d", jc.Version)
+	}
+
+	if err := checkCanvasSize(jc.Width, jc.Height); err != nil {
+		return nil, err
+	}
+
+	n := jc.Width * jc.Height
+	if len(jc.Glyphs) != jc.Height || len(jc.FG) != n || len(jc.BG) != n || len(jc.Flags) != n || len(jc.Colors) != n {
+		return nil, fmt.Errorf("Canvas data does not match its size: %dx%d", jc.Width, jc.Height)
+	}
+
+	canvas := &Canvas {
+		Width: jc.Width,
+		Height: jc.Height,
+		AspectRatio: jc.AspectRatio,
+		EdgesDetected: jc.EdgesDetected,
+		Settings: jc.Settings,
+	}
+
+	cells := make([]Cell, n)
+	canvas.Cells = make([][]Cell, jc.Height)
+
+	for y := range jc.Height {
+		canvas.Cells[y] = cells[y * jc.Width : (y + 1) * jc.Width]
+
+		glyphs := []rune(jc.Glyphs[y])
+		if len(glyphs) != jc.Width {
+			return nil, fmt.Errorf("Row %d has %d glyphs, expected %d", y, len(glyphs), jc.Width)
+		}
+
+		for x := range jc.Width {
+			idx := x + y * jc.Width
+			cell := &canvas.Cells[y][x]
+			cell.Rune = glyphs[x]
+			applyCellFlags(cell, jc.Flags[idx])
+
+			var err error
+			if cell.FG, err = parseJSONColor(jc.FG[idx]); err != nil {
+				return nil, err
+			}
+			if cell.BG, err = parseJSONColor(jc.BG[idx]); err != nil {
+				return nil, err
+			}
+
+			if colorIdx := jc.Colors[idx]; colorIdx >= 0 {
+				if colorIdx >= len(jc.Palette) {
+					return nil, fmt.Errorf("Colour index %d is out of range of the palette", colorIdx)
+				}
+				cell.ColorCode = jc.Palette[colorIdx].Code
+				cell.ColorEscape = jc.Palette[colorIdx].Escape
+			}
+		}
+	}
+
+	return canvas, nil
+}
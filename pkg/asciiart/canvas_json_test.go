@@ -0,0 +1,142 @@
+package asciiart
+
+import (
+	"encoding/json"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTripCanvas is a canvas using every field of Cell and CanvasSettings
+func roundTripCanvas() *Canvas {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	canvas := testCanvas(
+		[]Cell{
+			{Rune: 'a', FG: red, ColorCode: 31, ColorEscape: "\x1b[31m"},
+			{Rune: '|', FG: red, ColorCode: 31, ColorEscape: "\x1b[31m", Bold: true, IsEdge: true},
+			{Rune: '█', FG: blue, BG: blue},
+		},
+		[]Cell{
+			{Rune: ' ', Transparent: true},
+			{Rune: '"', FG: blue, ColorCode: 0x0000ff, ColorEscape: "\x1b[38;2;0;0;255m"},
+			{Rune: '<', FG: color.RGBA{1, 2, 3, 255}},
+		},
+	)
+	canvas.AspectRatio = 2.5
+	canvas.EdgesDetected = true
+	canvas.Settings = CanvasSettings {
+		OutputAspectRatio: 2,
+		Brightness: 0.25,
+		Contrast: -0.5,
+		Gamma: 1.5,
+		LinearLight: true,
+		TerminalBackground: TerminalBackgrounds.Light(),
+		LuminanceModel: LuminanceModels.Rec601(),
+		AlphaPolicy: AlphaPolicies.Transparent(),
+		UseSobel: true,
+		SobelMagnitudeSqThresholdNormalized: 10000,
+		SobelLaplacianThresholdNormalized: 128,
+		EdgeColorMode: EdgeColorModes.Lab(),
+		LuminosityFilterCount: 1,
+		PreFilterCount: 2,
+	}
+	return canvas
+}
+
+// convertedCanvas is a canvas generated by the pipeline with non default settings
+func convertedCanvas() *Canvas {
+	a := New(
+		WithOutputAspectRatio(1),
+		WithDefault8BitColorMapper(),
+		WithLuminanceModel(LuminanceModels.Custom(1, 2, 3)),
+		WithEdgeColorMode(EdgeColorModes.RGB()),
+		WithLuminosityFilters(HistogramEqualizationFilter()),
+		WithBrightness(0.1),
+	)
+	return a.ConvertToGrid(gradientImage(), 4, 2)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for name, canvas := range map[string]*Canvas{"every field": roundTripCanvas(), "converted": convertedCanvas(), "empty": testCanvas([]Cell{})} {
+		t.Run(name, func(t *testing.T) {
+			encoded := renderToString(t, JSONRenderer(), canvas)
+
+			decoded, err := DecodeCanvasJSON(strings.NewReader(encoded))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, canvas) {
+				t.Errorf("decoded canvas differs\ngot  %+v\nwant %+v", decoded, canvas)
+			}
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(renderToString(t, JSONRenderer(), roundTripCanvas())), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc["version"] != float64(1) {
+		t.Errorf("version = %v, want 1", doc["version"])
+	}
+
+	glyphs := doc["glyphs"].([]any)
+	if glyphs[0] != "a|█" || glyphs[1] != " \"<" {
+		t.Errorf("glyphs = %q", glyphs)
+	}
+
+	// The two red cells share a palette entry
+	if palette := doc["palette"].([]any); len(palette) != 2 {
+		t.Errorf("palette has %d entries, want 2", len(palette))
+	}
+
+	settings := doc["settings"].(map[string]any)
+	model := settings["luminanceModel"].(map[string]any)
+	if weights := model["weights"].([]any); weights[0] != float64(2990) || weights[1] != float64(5870) || weights[2] != float64(1140) {
+		t.Errorf("luminance weights = %v", weights)
+	}
+	if settings["useSobel"] != true || settings["alphaPolicy"] != float64(2) || settings["edgeColorMode"] != float64(2) || settings["preFilters"] != float64(2) {
+		t.Errorf("settings = %v", settings)
+	}
+}
+
+func TestDecodeCanvasJSONRejectsBadInput(t *testing.T) {
+	valid := `{"version":1,"width":1,"height":1,"glyphs":["a"],"fg":["#ff0000"],"bg":[""],"flags":[0],"palette":[{"code":31,"escape":"x"}],"colors":[0]}`
+	if _, err := DecodeCanvasJSON(strings.NewReader(valid)); err != nil {
+		t.Fatalf("valid input was rejected: %s", err)
+	}
+
+	tests := []struct {
+		name	string
+		input	string
+	}{
+		{"not json", `{"version":`},
+		{"version 0", strings.Replace(valid, `"version":1`, `"version":0`, 1)},
+		{"future version", strings.Replace(valid, `"version":1`, `"version":2`, 1)},
+		{"negative width", strings.Replace(valid, `"width":1`, `"width":-1`, 1)},
+		{"product overflows", `{"version":1,"width":4611686018427387904,"height":4,"glyphs":["","","",""],"fg":[],"bg":[],"flags":[],"colors":[],"palette":[]}`},
+		{"too many cells", `{"version":1,"width":4096,"height":4096,"glyphs":[],"fg":[],"bg":[],"flags":[],"colors":[],"palette":[]}`},
+		{"too wide", `{"version":1,"width":8388608,"height":1,"glyphs":[],"fg":[],"bg":[],"flags":[],"colors":[],"palette":[]}`},
+		{"missing cells", strings.Replace(valid, `"fg":["#ff0000"]`, `"fg":[]`, 1)},
+		{"short row", strings.Replace(valid, `"glyphs":["a"]`, `"glyphs":[""]`, 1)},
+		{"long row", strings.Replace(valid, `"glyphs":["a"]`, `"glyphs":["ab"]`, 1)},
+		{"bad colour", strings.Replace(valid, `"#ff0000"`, `"red"`, 1)},
+		{"short colour", strings.Replace(valid, `"#ff0000"`, `"#ff000"`, 1)},
+		{"palette index out of range", strings.Replace(valid, `"colors":[0]`, `"colors":[1]`, 1)},
+		{"negative luminance weight", strings.Replace(valid, `"version":1,`, `"version":1,"settings":{"luminanceModel":{"weights":[-1,5000,5001]}},`, 1)},
+		{"luminance weights do not sum to the scale", strings.Replace(valid, `"version":1,`, `"version":1,"settings":{"luminanceModel":{"weights":[1,2,3]}},`, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if canvas, err := DecodeCanvasJSON(strings.NewReader(tt.input)); err == nil {
+				t.Errorf("decoded %+v, want an error", canvas)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package asciiart
 import (
 	"fmt"
 	"image/color"
+	"strconv"
+	"strings"
 )
 
 func giveRewards(awards []int, minRange, defaultRew, r, g, b int) (int, int, int) {
@@ -42,6 +44,70 @@ func format24bitCode(r, g, b int)string {
 	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", r, g, b)
 }
 
+// ansi16Colors are the RGB values of the 16 standard ANSI colours in xterm's default palette, indexed 0-7 for the normal and 8-15 for the bright colours
+var ansi16Colors = [16]color.RGBA {
+	{0, 0, 0, 255}, {205, 0, 0, 255}, {0, 205, 0, 255}, {205, 205, 0, 255},
+	{0, 0, 238, 255}, {205, 0, 205, 255}, {0, 205, 205, 255}, {229, 229, 229, 255},
+	{127, 127, 127, 255}, {255, 0, 0, 255}, {0, 255, 0, 255}, {255, 255, 0, 255},
+	{92, 92, 255, 255}, {255, 0, 255, 255}, {0, 255, 255, 255}, {255, 255, 255, 255},
+}
+
+// ansi256Color returns the RGB value of code in xterm's default 256 colour palette
+func ansi256Color(code int) color.RGBA {
+	if code < 16 {
+		return ansi16Colors[code]
+	}
+
+	if code >= 232 {
+		grey := uint8(8 + (code - 232) * 10)
+		return color.RGBA{grey, grey, grey, 255}
+	}
+
+	// The 6x6x6 cube, with levels 0, 95, 135, 175, 215 and 255
+	level := func(i int) uint8 {
+		if i == 0 {
+			return 0
+		}
+		return uint8(55 + i * 40)
+	}
+	code -= 16
+	return color.RGBA{level(code / 36), level(code / 6 % 6), level(code % 6), 255}
+}
+
+/*
+ansiEscapeColor returns the colour the foreground escape sequence escape is displayed with in xterm's default palette. It understands the sequences of the library color mappers (30-37, 90-97, 38;5;n and 38;2;r;g;b), and returns false for anything else.
+*/
+func ansiEscapeColor(escape string) (color.RGBA, bool) {
+	inner, ok := strings.CutPrefix(escape, "\x1b[")
+	inner, hasSuffix := strings.CutSuffix(inner, "m")
+	if !ok || !hasSuffix {
+		return color.RGBA{}, false
+	}
+
+	params := strings.Split(inner, ";")
+	codes := make([]int, len(params))
+	for i, param := range params {
+		code, err := strconv.Atoi(param)
+		if err != nil || code < 0 || code > 255 {
+			return color.RGBA{}, false
+		}
+		codes[i] = code
+	}
+
+	switch {
+		case len(codes) == 1 && codes[0] >= 30 && codes[0] <= 37:
+			return ansi16Colors[codes[0] - 30], true
+		case len(codes) == 1 && codes[0] >= 90 && codes[0] <= 97:
+			return ansi16Colors[codes[0] - 90 + 8], true
+		case len(codes) == 3 && codes[0] == 38 && codes[1] == 5:
+			return ansi256Color(codes[2]), true
+		case len(codes) == 5 && codes[0] == 38 && codes[1] == 2:
+			return color.RGBA{uint8(codes[2]), uint8(codes[3]), uint8(codes[4]), 255}, true
+	}
+
+	return color.RGBA{}, false
+}
+
 func channelSplit(c color.Color) (int, int, int) {
 	r, g, b, a := c.RGBA()
 	a8uint := a >> 8
@@ -52,8 +118,14 @@ func channelSplit(c color.Color) (int, int, int) {
 	return r8, g8, b8
 }
 
+/*
+default3BitColorMapperFactory builds the 3 bit color mapper for a terminal with the background bg. The converter builds its library color mappers for its own TerminalBackground when a conversion starts (see colorMapper()), so the order of the options does not matter.
+
+On a light background the black/white clamps are mirrored: BlackLumUpper and WhiteLumLower are measured from white instead of black, so the brightest pixels blend into the background as white (37) and the darkest are drawn as black (30).
+*/
 func default3BitColorMapperFactory(
 	opts ColorMapper3BitOptions,
+	bg TerminalBackground,
 ) func(LuminosityProvider LuminosityProvider, x, y int) (int, string) {
 
 	return func(lumProv LuminosityProvider, x, y int) (int, string) {
@@ -62,13 +134,24 @@ func default3BitColorMapperFactory(
 
 		code := 0
 
-		// Check if the pixel is too dark or too bright and just assign it to black/white without doing further calculations
-		if lum <= opts.BlackLumUpper {
-			code = 30
-			return code, format4bitCode(code)
-		} else if lum >= opts.WhiteLumLower {
-			code = 37
-			return code, format4bitCode(code)
+		if isLightBackground(bg) {
+			lum = 255 - lum
+			if lum <= opts.BlackLumUpper {
+				code = 37
+				return code, format4bitCode(code)
+			} else if lum >= opts.WhiteLumLower {
+				code = 30
+				return code, format4bitCode(code)
+			}
+		} else {
+			// Check if the pixel is too dark or too bright and just assign it to black/white without doing further calculations
+			if lum <= opts.BlackLumUpper {
+				code = 30
+				return code, format4bitCode(code)
+			} else if lum >= opts.WhiteLumLower {
+				code = 37
+				return code, format4bitCode(code)
+			}
 		}
 		
 		if opts.DoReward {
@@ -93,28 +176,51 @@ func default3BitColorMapperFactory(
 	}
 }
 
-func default4BitColorMapperFactory(opts ColorMapper4BitOptions) func(LuminosityProvider, int, int) (int, string) {
+/*
+default4BitColorMapperFactory builds the 4 bit color mapper. See default3BitColorMapperFactory() for bg.
+
+On a light background the clamps are mirrored like the 3 bit mapper, and so are the bright variants: the brightest pixels use bright white (97), pixels just inside BlackLumUpper are lifted to white (37) so they stay visible, the darkest pixels use black (30) and the rest of the white clamp uses bright black (90). Coloured pixels use the normal (darker) codes when they are dark, rather than when they are bright.
+*/
+func default4BitColorMapperFactory(opts ColorMapper4BitOptions, bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
 
 	return func(lumProv LuminosityProvider, x, y int) (int, string) {
 		r8, g8, b8 := channelSplit(lumProv.At(x, y))
 		lum := lumProv.LuminosityAt(x, y)
+		light := isLightBackground(bg)
 
 		code := 0
 
-		// Check if the pixel is too dark or too bright and just assign it to black/white without doing further calculations
-		if lum <= opts.BlackLumUpper {
-			code = 30
-			if lum >= opts.BoldBlackLumLower {
-				code += 60
+		if light {
+			lum = 255 - lum
+			if lum <= opts.BlackLumUpper {
+				code = 97
+				if lum >= opts.BoldBlackLumLower {
+					code = 37
+				}
+				return code, format4bitCode(code)
+			} else if lum >= opts.WhiteLumLower {
+				code = 90
+				if lum >= opts.BoldWhiteLumLower {
+					code = 30
+				}
 				return code, format4bitCode(code)
 			}
-			return code, format4bitCode(code)
-		} else if lum >= opts.WhiteLumLower {
-			code = 37
-			if lum >= opts.BoldWhiteLumLower {
-				code += 60
+		} else {
+			// Check if the pixel is too dark or too bright and just assign it to black/white without doing further calculations
+			if lum <= opts.BlackLumUpper {
+				code = 30
+				if lum >= opts.BoldBlackLumLower {
+					code += 60
+					return code, format4bitCode(code)
+				}
+				return code, format4bitCode(code)
+			} else if lum >= opts.WhiteLumLower {
+				code = 37
+				if lum >= opts.BoldWhiteLumLower {
+					code += 60
+				}
+				return code, format4bitCode(code)
 			}
-			return code, format4bitCode(code)
 		}
 		
 		if opts.DoReward {
@@ -136,9 +242,17 @@ func default4BitColorMapperFactory(opts ColorMapper4BitOptions) func(LuminosityP
 
 
 		if lum >= opts.BoldColoredLumLower {
-			code += 90
+			if light {
+				code += 30
+			} else {
+				code += 90
+			}
 		} else {
-			code += 30
+			if light {
+				code += 90
+			} else {
+				code += 30
+			}
 		}
 
 		return code, format4bitCode(code)
@@ -153,7 +267,12 @@ func populateSteps(dest []int, rule [3]int) {
 	}
 }
 
-func default8BitColorMapperFactory(opts ColorMapper8BitOptions) func(LuminosityProvider, int, int) (int, string) {
+/*
+default8BitColorMapperFactory builds the 8 bit color mapper for a terminal with the background bg. See default3BitColorMapperFactory() for bg.
+
+Every pixel is matched to the closest colour of the 6x6x6 cube or the grey ramp. On a light background the black/white clamps are mirrored like the 3 bit mapper: pixels within BlackLumUpper of white are clamped to white (231), so they blend into the background, and pixels within WhiteLumLower of white are clamped to black (16). On a dark background nothing is clamped.
+*/
+func default8BitColorMapperFactory(opts ColorMapper8BitOptions, bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
 
 	rSteps, gSteps, bSteps, greySteps := [6]int{}, [6]int{}, [6]int{}, [24]int{}
 	populateSteps(rSteps[:], opts.rStep)
@@ -161,8 +280,21 @@ func default8BitColorMapperFactory(opts ColorMapper8BitOptions) func(LuminosityP
 	populateSteps(bSteps[:], opts.bStep)
 	populateSteps(greySteps[:], opts.greyStep)
 
+	const black8Bit, white8Bit = 16, 231
+
 	return func(lumProv LuminosityProvider, x, y int) (int, string) {
 		r8, g8, b8 := channelSplit(lumProv.At(x, y))
+		lum := lumProv.LuminosityAt(x, y)
+
+		// The palette already has black and white, so pixels are only clamped on a light background, where they are mirrored
+		if isLightBackground(bg) {
+			lum = 255 - lum
+			if lum <= opts.BlackLumUpper {
+				return white8Bit, format8bitCode(white8Bit)
+			} else if lum >= opts.WhiteLumLower {
+				return black8Bit, format8bitCode(black8Bit)
+			}
+		}
 
 		// Map to 6x6x6 cube
 		r8dist := r8 - opts.rStep[1]
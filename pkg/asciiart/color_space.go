@@ -0,0 +1,73 @@
+package asciiart
+
+import "math"
+
+// srgbDecodeTable maps an 8 bit sRGB encoded channel onto linear light (0-1)
+var srgbDecodeTable = func() [256]float64 {
+	var table [256]float64
+	for i := range table {
+		table[i] = srgbToLinear(float64(i) / 255)
+	}
+	return table
+}()
+
+// srgbToLinear decodes an sRGB encoded channel (0-1) to linear light (0-1)
+func srgbToLinear(c float64) float64 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c + 0.055) / 1.055, 2.4)
+}
+
+/*
+rgbToLab converts an 8 bit sRGB colour to CIE L*a*b* (D65 white point).
+	- L is in [0, 100]
+	- a and b are roughly in [-128, 127]
+*/
+func rgbToLab(r8, g8, b8 int) (float64, float64, float64) {
+	r, g, b := srgbDecodeTable[r8], srgbDecodeTable[g8], srgbDecodeTable[b8]
+
+	// Linear sRGB -> XYZ, normalised by the D65 reference white
+	x := (0.4124564 * r + 0.3575761 * g + 0.1804375 * b) / 0.95047
+	y := 0.2126729 * r + 0.7151522 * g + 0.0721750 * b
+	z := (0.0193339 * r + 0.1191920 * g + 0.9503041 * b) / 1.08883
+
+	fx, fy, fz := labF(x), labF(y), labF(z)
+
+	return 116 * fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+func labF(t float64) float64 {
+	const delta = 6.0 / 29.0
+	if t > delta * delta * delta {
+		return math.Cbrt(t)
+	}
+	return t / (3 * delta * delta) + 4.0 / 29.0
+}
+
+// srgbEncodeTableSize is the number of entries in srgbEncodeTable. 4096 entries keeps the error within a single 8 bit step
+const srgbEncodeTableSize = 4096
+
+// srgbEncodeTable maps linear light (0-1, quantised to srgbEncodeTableSize steps) onto an 8 bit sRGB encoded channel
+var srgbEncodeTable = func() [srgbEncodeTableSize]uint8 {
+	var table [srgbEncodeTableSize]uint8
+	for i := range table {
+		c := linearToSRGB(float64(i) / (srgbEncodeTableSize - 1))
+		table[i] = uint8(min(255, max(0, math.Round(c * 255))))
+	}
+	return table
+}()
+
+// linearToSRGB encodes linear light (0-1) to an sRGB encoded channel (0-1)
+func linearToSRGB(c float64) float64 {
+	if c <= 0.0031308 {
+		return c * 12.92
+	}
+	return 1.055 * math.Pow(c, 1 / 2.4) - 0.055
+}
+
+// linearToSRGB8 encodes linear light (0-1) to an 8 bit sRGB encoded channel
+func linearToSRGB8(c float64) uint8 {
+	idx := int(c * (srgbEncodeTableSize - 1) + 0.5)
+	return srgbEncodeTable[min(srgbEncodeTableSize - 1, max(0, idx))]
+}
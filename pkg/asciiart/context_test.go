@@ -0,0 +1,139 @@
+package asciiart
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/png"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// contextOptions are the converter settings the context tests run with, covering every stage of the pipeline
+var contextOptions = map[string][]AsciiOption{
+	"default": nil,
+	"no sobel": {WithSobel(false)},
+	"colour": {WithDefault8BitColorMapper()},
+	"linear light": {WithLinearLight(true), WithSobel(true)},
+	"parallel": {WithParallelism(4)},
+}
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gradientImage()); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestConvertContextDone(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	contexts := []struct {
+		name	string
+		ctx		context.Context
+		want	error
+	}{
+		{"cancelled", cancelled, context.Canceled},
+		{"deadline passed", expired, context.DeadlineExceeded},
+	}
+
+	encoded := pngBytes(t)
+
+	for _, c := range contexts {
+		for name, opts := range contextOptions {
+			t.Run(c.name + "/" + name, func(t *testing.T) {
+				a := New(opts...)
+
+				if out, err := a.ConvertContext(c.ctx, gradientImage(), 4, 2); !errors.Is(err, c.want) || out != "" {
+					t.Errorf("ConvertContext() = %q, %v, want no output and %v", out, err, c.want)
+				}
+				if out, err := a.ConvertReaderContext(c.ctx, bytes.NewReader(encoded), 4, 2); !errors.Is(err, c.want) || out != "" {
+					t.Errorf("ConvertReaderContext() = %q, %v, want no output and %v", out, err, c.want)
+				}
+				if out, err := a.ConvertBytesContext(c.ctx, encoded, 4, 2); !errors.Is(err, c.want) || out != "" {
+					t.Errorf("ConvertBytesContext() = %q, %v, want no output and %v", out, err, c.want)
+				}
+			})
+		}
+	}
+}
+
+// countdownContext is cancelled once Err() has been called a number of times, so a conversion can be cancelled part way through
+type countdownContext struct {
+	context.Context
+	remaining	atomic.Int64
+}
+
+func newCountdownContext(calls int64) *countdownContext {
+	ctx := &countdownContext{Context: context.Background()}
+	ctx.remaining.Store(calls)
+	return ctx
+}
+
+func (c *countdownContext) Err() error {
+	if c.remaining.Add(-1) < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestConvertContextCancelledPartWay(t *testing.T) {
+	img := loadSampleImage(t, "1-shyguy.png")
+
+	for name, opts := range contextOptions {
+		t.Run(name, func(t *testing.T) {
+			a := New(opts...)
+
+			// Count how often a full conversion checks for cancellation, then cancel at points throughout it
+			counter := newCountdownContext(1 << 30)
+			if _, err := a.ConvertContext(counter, img, 60, 30); err != nil {
+				t.Fatal(err)
+			}
+			checks := 1 << 30 - counter.remaining.Load()
+			if checks < 2 {
+				t.Fatalf("conversion checked for cancellation %d times, want once per stage at least", checks)
+			}
+
+			for _, calls := range []int64{0, 1, checks / 4, checks / 2, checks - 1} {
+				out, err := a.ConvertContext(newCountdownContext(calls), img, 60, 30)
+				if !errors.Is(err, context.Canceled) || out != "" {
+					t.Errorf("cancelled after %d of %d checks: got %q, %v, want no output and %v", calls, checks, out, err, context.Canceled)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertMatchesConvertContext(t *testing.T) {
+	images := map[string]string{
+		"png": "1-shyguy.png",
+		"jpeg": "3-mona_lisa.jpg",
+	}
+
+	for imgName, file := range images {
+		img := loadSampleImage(t, file)
+
+		for name, opts := range contextOptions {
+			t.Run(imgName + "/" + name, func(t *testing.T) {
+				a := New(opts...)
+
+				want := a.Convert(img, 80, 40)
+				got, err := a.ConvertContext(context.Background(), img, 80, 40)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("ConvertContext() output differs from Convert()")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package asciiart
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+)
+
+/*
+ApplySobelSupersampled returns the defaultSobelProvider implementation of SobelProvider for lumImg, but computes the sobel data from src (the image before downscaling) instead of the downscaled luminosity.
+
+src is resampled to EdgeSupersampling samples per character along each axis (clamped to the resolution of src), the sobel kernel is run over that intermediate image, and the results are pooled back into each character:
+	- The magnitude squared of a character is the strongest magnitude squared of any of its samples, so a thin edge is not averaged away by the flat samples around it.
+	- The gradient of a character is the dominant orientation of its samples (the principal direction of the summed structure tensor), converted back into character space. Opposing gradients on either side of a line reinforce rather than cancel each other.
+	- The laplacian of a character is the laplacian of its strongest sample.
+
+If EdgeSupersampling <= 1 (or src is not larger than lumImg), this is equivalent to ApplySobel(lumImg, aspect_ratio).
+*/
+func (a *AsciiConverter) ApplySobelSupersampled(src image.Image, lumImg LuminosityProvider, aspect_ratio float64) defaultSobelProvider {
+	// context.Background() is never cancelled, so there is no error
+	sobelProv, _ := a.applySobelSupersampled(context.Background(), src, lumImg, aspect_ratio)
+	return sobelProv
+}
+
+// applySobelSupersampled is ApplySobelSupersampled(), stopping with ctx.Err() if ctx is cancelled
+func (a *AsciiConverter) applySobelSupersampled(ctx context.Context, src image.Image, lumImg LuminosityProvider, aspect_ratio float64) (defaultSobelProvider, error) {
+	outWidth, outHeight := lumImg.Width(), lumImg.Height()
+	srcBounds := src.Bounds()
+
+	// Number of samples per character along each axis. Each character is covered by exactly samplesX * samplesY samples
+	samplesX := max(1, min(a.EdgeSupersampling, srcBounds.Dx() / outWidth))
+	samplesY := max(1, min(a.EdgeSupersampling, srcBounds.Dy() / outHeight))
+
+	if samplesX == 1 && samplesY == 1 {
+		return a.applySobel(ctx, lumImg, aspect_ratio)
+	}
+
+	sampledWidth := outWidth * samplesX
+	sampledImg, err := a.resampleImage(ctx, src, sampledWidth, outHeight * samplesY)
+	if err != nil {
+		return defaultSobelProvider{}, err
+	}
+
+	sampledLum, err := a.mapLuminosity(ctx, sampledImg)
+	if err != nil {
+		return defaultSobelProvider{}, err
+	}
+
+	// A sample is 1/samplesX of a character wide and 1/samplesY of a character tall
+	sampleAspectRatio := aspect_ratio * float64(samplesX) / float64(samplesY)
+	sampledSobel, err := a.applySobel(ctx, sampledLum, sampleAspectRatio)
+	if err != nil {
+		return defaultSobelProvider{}, err
+	}
+
+	gLen := outWidth * outHeight
+	gMag2 := make([]int, gLen)
+	gGrad := make([]float64, gLen)
+	gLap := make([]float64, gLen)
+
+	err = a.parallelRows(ctx, outHeight, func(y int) {
+		for x := range outWidth {
+			// Summed structure tensor of the samples, in character space
+			var gxx, gyy, gxy float64
+			strongest := -1
+			strongestIdx := 0
+
+			for sy := y * samplesY; sy < (y + 1) * samplesY; sy++ {
+				for sx := x * samplesX; sx < (x + 1) * samplesX; sx++ {
+					sIdx := sx + sy * sampledWidth
+					mag2 := sampledSobel.SobelMag2At1D(sIdx)
+					if mag2 > strongest {
+						strongest = mag2
+						strongestIdx = sIdx
+					}
+
+					if mag2 == 0 {
+						continue
+					}
+
+					gx, gy := gradComponents(sampledSobel.SobelGradAt1D(sIdx), mag2)
+					// A sample is 1/samplesX of a character wide and 1/samplesY of a character tall
+					gx *= float64(samplesX)
+					gy *= float64(samplesY)
+
+					gxx += gx * gx
+					gyy += gy * gy
+					gxy += gx * gy
+				}
+			}
+
+			idx := x + y * outWidth
+			theta := 0.5 * math.Atan2(2 * gxy, gxx - gyy)
+
+			gMag2[idx] = strongest
+			gGrad[idx] = computeGrad(math.Cos(theta), math.Sin(theta))
+			gLap[idx] = sampledSobel.SobelLaplacianAt1D(strongestIdx)
+		}
+	})
+	if err != nil {
+		return defaultSobelProvider{}, err
+	}
+
+	return makeDefaultSobelProvider(lumImg, gGrad, gMag2, gLap), nil
+}
+
+/*
+gradComponents recovers gx and gy (up to a shared sign) from a gradient (gy/gx) and a magnitude squared (gx^2 + gy^2).
+*/
+func gradComponents(grad float64, mag2 int) (float64, float64) {
+	mag := math.Sqrt(float64(mag2))
+	if math.IsInf(grad, 0) {
+		return 0, mag
+	}
+
+	gx := mag / math.Sqrt(1 + grad * grad)
+	return gx, grad * gx
+}
+
+/*
+edgeChannels splits lumImg into the channels that sobel edge detection should run on (depending on EdgeColorMode), along with the factor to scale the combined magnitude squared by, so that the sobel thresholds mean roughly the same thing in every mode.
+*/
+func (a *AsciiConverter) edgeChannels(lumImg LuminosityProvider) ([]LuminosityProvider, float64) {
+	switch a.EdgeColorMode {
+		case EdgeColorModes.Luminosity():
+			return []LuminosityProvider{lumImg}, 1
+		case EdgeColorModes.RGB():
+			// A grey edge appears equally in all 3 channels, so average them to match luminosity mode
+			return splitChannels(lumImg, func(r8, g8, b8 int) (int, int, int) {
+				return r8, g8, b8
+			}), 1.0 / 3.0
+		case EdgeColorModes.Lab():
+			// L* is rescaled to 0-255 so a grey edge is as strong as in luminosity mode. a* and b* are centered on 128
+			return splitChannels(lumImg, func(r8, g8, b8 int) (int, int, int) {
+				l, aStar, bStar := rgbToLab(r8, g8, b8)
+				return clampLuminosity(l * 2.55), clampLuminosity(aStar + 128), clampLuminosity(bStar + 128)
+			}), 1
+		default:
+			msg := fmt.Sprintf("Unknown edge color mode provided: %d", a.EdgeColorMode)
+			panic(msg)
+	}
+}
+
+/*
+splitChannels builds 3 single channel luminosity grids from the colour of each character in lumImg, using convert to map an 8 bit r, g, b triplet onto the 3 channel values (0-255).
+*/
+func splitChannels(lumImg LuminosityProvider, convert func(r8, g8, b8 int) (int, int, int)) []LuminosityProvider {
+	c0 := makeDefaultLuminosityImage(lumImg)
+	c1 := makeDefaultLuminosityImage(lumImg)
+	c2 := makeDefaultLuminosityImage(lumImg)
+
+	width, height := lumImg.Width(), lumImg.Height()
+	for y := range height {
+		for x := range width {
+			v0, v1, v2 := convert(channelSplit(lumImg.At(x, y)))
+
+			c0.LuminositySet(x, y, v0)
+			c1.LuminositySet(x, y, v1)
+			c2.LuminositySet(x, y, v2)
+		}
+	}
+
+	return []LuminosityProvider{c0, c1, c2}
+}
@@ -0,0 +1,197 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// thinLineImage returns a black width x height image with a 1 pixel wide white vertical line at column lineX
+func thinLineImage(width, height, lineX int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := range height {
+		img.SetGray(lineX, y, color.Gray{Y: 255})
+	}
+	return img
+}
+
+// maxMag2 returns the largest magnitude squared of any character in sobelProv
+func maxMag2(sobelProv SobelProvider) int {
+	strongest := 0
+	for y := range sobelProv.Height() {
+		for x := range sobelProv.Width() {
+			strongest = max(strongest, sobelProv.SobelMag2At(x, y))
+		}
+	}
+	return strongest
+}
+
+func TestApplySobelSupersampledFindsThinLines(t *testing.T) {
+	a := New(WithSourceResolutionEdges())
+
+	// Find a line the nearest neighbour downscale skips entirely, so only supersampling can see it
+	var src *image.Gray
+	var lumImg LuminosityProvider
+	var aspectRatio float64
+	for lineX := 80; lineX < 84; lineX++ {
+		candidate := thinLineImage(160, 80, lineX)
+		downscaled, ar := a.DownscaleImage(candidate, 40, 40)
+		candidateLum := a.MapLuminosity(downscaled)
+
+		if maxMag2(a.ApplySobel(candidateLum, ar)) == 0 {
+			src, lumImg, aspectRatio = candidate, candidateLum, ar
+			break
+		}
+	}
+	if src == nil {
+		t.Fatal("every line position survived downscaling")
+	}
+
+	sobelProv := a.ApplySobelSupersampled(src, lumImg, aspectRatio)
+	if sobelProv.Width() != lumImg.Width() || sobelProv.Height() != lumImg.Height() {
+		t.Fatalf("sobel grid is %dx%d, want %dx%d", sobelProv.Width(), sobelProv.Height(), lumImg.Width(), lumImg.Height())
+	}
+
+	// The line is pooled into a single column of characters, with a left-right gradient
+	y := sobelProv.Height() / 2
+	edgeCols := 0
+	for x := range sobelProv.Width() {
+		if sobelProv.SobelMag2At(x, y) == 0 {
+			continue
+		}
+
+		edgeCols++
+		if grad := sobelProv.SobelGradAt(x, y); math.Abs(grad) > 0.01 {
+			t.Errorf("gradient at (%d, %d) = %v, want roughly 0 for a vertical line", x, y, grad)
+		}
+	}
+	if edgeCols == 0 || edgeCols > 2 {
+		t.Errorf("line registered in %d columns, want 1 or 2", edgeCols)
+	}
+
+	// Flat characters well away from the line have no edge
+	if mag2 := sobelProv.SobelMag2At(2, y); mag2 != 0 {
+		t.Errorf("magnitude squared away from the line = %d, want 0", mag2)
+	}
+}
+
+func TestApplySobelSupersampledWithoutSupersampling(t *testing.T) {
+	src := thinLineImage(160, 80, 81)
+
+	for _, factor := range []int{0, 1} {
+		a := New(WithEdgeSupersampling(factor))
+		downscaled, aspectRatio := a.DownscaleImage(src, 40, 40)
+		lumImg := a.MapLuminosity(downscaled)
+
+		got := a.ApplySobelSupersampled(src, lumImg, aspectRatio)
+		want := a.ApplySobel(lumImg, aspectRatio)
+
+		for i := range want.G_Mag2 {
+			if got.G_Mag2[i] != want.G_Mag2[i] || got.G_Laplacian[i] != want.G_Laplacian[i] {
+				t.Fatalf("factor %d: supersampled sobel differs from ApplySobel() at %d", factor, i)
+			}
+		}
+	}
+}
+
+// splitImage returns a width x height image that is left in the left half and right in the right half
+func splitImage(width, height int, left, right color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			if x < width / 2 {
+				img.Set(x, y, left)
+			} else {
+				img.Set(x, y, right)
+			}
+		}
+	}
+	return img
+}
+
+func TestEdgeColorModes(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+
+	// Find the grey with the same luminosity as red, so the boundary between them is invisible to luminosity edges
+	a := New()
+	redLum := a.MapLuminosity(splitImage(2, 1, red, red)).LuminosityAt(0, 0)
+	var grey color.NRGBA
+	found := false
+	for g := range 256 {
+		grey = color.NRGBA{R: uint8(g), G: uint8(g), B: uint8(g), A: 255}
+		if a.MapLuminosity(splitImage(2, 1, grey, grey)).LuminosityAt(0, 0) == redLum {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatalf("no grey has the luminosity of red (%d)", redLum)
+	}
+
+	isoluminant := splitImage(12, 6, red, grey)
+	greyStep := splitImage(12, 6, color.NRGBA{A: 255}, color.NRGBA{R: 200, G: 200, B: 200, A: 255})
+
+	tests := []struct {
+		name		string
+		mode		EdgeColorMode
+		img			image.Image
+		wantEdge	bool
+	}{
+		{"luminosity misses isoluminant edge", EdgeColorModes.Luminosity(), isoluminant, false},
+		{"rgb finds isoluminant edge", EdgeColorModes.RGB(), isoluminant, true},
+		{"lab finds isoluminant edge", EdgeColorModes.Lab(), isoluminant, true},
+		{"luminosity finds grey edge", EdgeColorModes.Luminosity(), greyStep, true},
+		{"rgb finds grey edge", EdgeColorModes.RGB(), greyStep, true},
+		{"lab finds grey edge", EdgeColorModes.Lab(), greyStep, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(WithEdgeColorMode(tt.mode))
+			sobelProv := a.ApplySobel(a.MapLuminosity(tt.img), 1)
+
+			// Columns 5 and 6 are either side of the boundary
+			mag2 := sobelProv.SobelMag2At(5, 3)
+			if gotEdge := mag2 > 0; gotEdge != tt.wantEdge {
+				t.Errorf("magnitude squared at the boundary = %d, want edge %v", mag2, tt.wantEdge)
+			}
+			if tt.wantEdge {
+				if grad := sobelProv.SobelGradAt(5, 3); math.Abs(grad) > 0.01 {
+					t.Errorf("gradient at the boundary = %v, want roughly 0", grad)
+				}
+			}
+			if mag2 := sobelProv.SobelMag2At(1, 3); mag2 != 0 {
+				t.Errorf("magnitude squared away from the boundary = %d, want 0", mag2)
+			}
+		})
+	}
+}
+
+func TestRGBEdgeColorModeMatchesLuminosityOnGrey(t *testing.T) {
+	img := splitImage(12, 6, color.NRGBA{R: 40, G: 40, B: 40, A: 255}, color.NRGBA{R: 220, G: 220, B: 220, A: 255})
+
+	lumSobel := New().ApplySobel(New().MapLuminosity(img), 1)
+	rgb := New(WithEdgeColorMode(EdgeColorModes.RGB()))
+	rgbSobel := rgb.ApplySobel(rgb.MapLuminosity(img), 1)
+
+	for y := range 6 {
+		for x := range 12 {
+			lumMag2, rgbMag2 := lumSobel.SobelMag2At(x, y), rgbSobel.SobelMag2At(x, y)
+			if math.Abs(float64(lumMag2 - rgbMag2)) > 0.02 * float64(lumMag2) {
+				t.Errorf("magnitude squared at (%d, %d) = %d in rgb mode, want %d as in luminosity mode", x, y, rgbMag2, lumMag2)
+			}
+		}
+	}
+}
+
+func TestUnknownEdgeColorModePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want a panic for an unknown edge color mode")
+		}
+	}()
+
+	a := New(WithEdgeColorMode(EdgeColorMode(99)))
+	a.ApplySobel(makeTestLuminosity(verticalStep(3, 3, 1, 0, 1)), 1)
+}
@@ -0,0 +1,143 @@
+package asciiart
+
+import "math"
+
+/*
+HistogramEqualizationFilter returns a LuminosityFilter that spreads the luminosity of the whole image over the full 0-255 range, so that every part of the character ramp gets used. Images that only use a narrow band of luminosity (e.g. screenshots or washed out photos) benefit the most.
+
+Use it with WithLuminosityFilters(). See CLAHEFilter() for a local variant that keeps more detail.
+*/
+func HistogramEqualizationFilter() LuminosityFilter {
+	return func(dst, src LuminosityProvider) {
+		width, height := src.Width(), src.Height()
+
+		var hist [256]int
+		for y := range height {
+			for x := range width {
+				hist[src.LuminosityAt(x, y)]++
+			}
+		}
+
+		mapping := equalizationMapping(&hist, width * height)
+
+		for y := range height {
+			for x := range width {
+				dst.LuminositySet(x, y, int(mapping[src.LuminosityAt(x, y)]))
+			}
+		}
+	}
+}
+
+/*
+CLAHEFilter returns a LuminosityFilter implementing contrast limited adaptive histogram equalization. The image is split into a tilesX x tilesY grid, and each tile is equalized separately, so dark and bright regions each get the full character ramp. The mapping of each character is bilinearly interpolated between the 4 closest tiles, so no seams are visible between tiles.
+	- clipLimit limits how much contrast can be added. Each histogram bin is clipped to clipLimit times the average bin height of the tile before equalizing, and the clipped excess is spread evenly over all bins. 1 gives no contrast enhancement, values between 2-4 are typical. Values <= 0 disable clipping (plain adaptive histogram equalization).
+
+tilesX and tilesY are clamped to [1, width] and [1, height] respectively.
+*/
+func CLAHEFilter(tilesX, tilesY int, clipLimit float64) LuminosityFilter {
+	return func(dst, src LuminosityProvider) {
+		width, height := src.Width(), src.Height()
+		tx := min(max(1, tilesX), width)
+		ty := min(max(1, tilesY), height)
+
+		// mappings[i + j * tx] is the equalization mapping of tile (i, j)
+		mappings := make([][256]uint8, tx * ty)
+		for j := range ty {
+			y0, y1 := j * height / ty, (j + 1) * height / ty
+			for i := range tx {
+				x0, x1 := i * width / tx, (i + 1) * width / tx
+
+				var hist [256]int
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						hist[src.LuminosityAt(x, y)]++
+					}
+				}
+
+				n := (x1 - x0) * (y1 - y0)
+				if clipLimit > 0 {
+					clipHistogram(&hist, max(1, int(clipLimit * float64(n) / 256)))
+				}
+
+				mappings[i + j * tx] = equalizationMapping(&hist, n)
+			}
+		}
+
+		tileWidth := float64(width) / float64(tx)
+		tileHeight := float64(height) / float64(ty)
+
+		for y := range height {
+			// Position relative to the tile centres, so that (0, 0) is the centre of the top left tile
+			fy := (float64(y) + 0.5) / tileHeight - 0.5
+			j0 := min(ty - 1, max(0, int(math.Floor(fy))))
+			j1 := min(ty - 1, j0 + 1)
+			wy := min(1, max(0, fy - float64(j0)))
+
+			for x := range width {
+				fx := (float64(x) + 0.5) / tileWidth - 0.5
+				i0 := min(tx - 1, max(0, int(math.Floor(fx))))
+				i1 := min(tx - 1, i0 + 1)
+				wx := min(1, max(0, fx - float64(i0)))
+
+				lum := src.LuminosityAt(x, y)
+				top := (1 - wx) * float64(mappings[i0 + j0 * tx][lum]) + wx * float64(mappings[i1 + j0 * tx][lum])
+				bottom := (1 - wx) * float64(mappings[i0 + j1 * tx][lum]) + wx * float64(mappings[i1 + j1 * tx][lum])
+
+				dst.LuminositySet(x, y, clampLuminosity((1 - wy) * top + wy * bottom))
+			}
+		}
+	}
+}
+
+/*
+equalizationMapping returns the mapping from luminosity to equalized luminosity for a histogram of n values, using the cumulative distribution of the histogram.
+*/
+func equalizationMapping(hist *[256]int, n int) [256]uint8 {
+	var mapping [256]uint8
+
+	// The lowest used luminosity is mapped to 0, so the full range is used
+	cdfMin := 0
+	for _, count := range hist {
+		if count != 0 {
+			cdfMin = count
+			break
+		}
+	}
+
+	if n - cdfMin <= 0 {
+		// Only a single luminosity is used, so there is nothing to spread out
+		for i := range mapping {
+			mapping[i] = uint8(i)
+		}
+		return mapping
+	}
+
+	cdf := 0
+	for i, count := range hist {
+		cdf += count
+		mapping[i] = uint8(clampLuminosity(float64(cdf - cdfMin) / float64(n - cdfMin) * 255))
+	}
+
+	return mapping
+}
+
+/*
+clipHistogram clips every bin of hist to limit, and redistributes the clipped excess evenly over all bins.
+*/
+func clipHistogram(hist *[256]int, limit int) {
+	excess := 0
+	for i, count := range hist {
+		if count > limit {
+			excess += count - limit
+			hist[i] = limit
+		}
+	}
+
+	perBin, remainder := excess / len(hist), excess % len(hist)
+	for i := range hist {
+		hist[i] += perBin
+		if i < remainder {
+			hist[i]++
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package asciiart
+
+import (
+	"testing"
+)
+
+// bandRows returns a width x height grid cycling through the luminosity values lo..lo+n-1 along each row
+func bandRows(width, height, lo, n int) [][]int {
+	rows := make([][]int, height)
+	for y := range rows {
+		rows[y] = make([]int, width)
+		for x := range rows[y] {
+			rows[y][x] = lo + x % n
+		}
+	}
+	return rows
+}
+
+func TestHistogramEqualizationFilter(t *testing.T) {
+	tests := []struct {
+		name	string
+		rows	[][]int
+		// want maps an input luminosity onto its expected output
+		want	map[int]int
+	}{
+		{"narrow band spreads to full range", bandRows(8, 2, 100, 4), map[int]int{100: 0, 101: 85, 102: 170, 103: 255}},
+		{"full range is kept", bandRows(4, 1, 0, 4), map[int]int{0: 0, 1: 85, 2: 170, 3: 255}},
+		{"single luminosity is untouched", bandRows(4, 4, 77, 1), map[int]int{77: 77}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyFilter(HistogramEqualizationFilter(), tt.rows)
+
+			for y, row := range tt.rows {
+				for x, lum := range row {
+					if want, ok := tt.want[lum]; ok && got[y][x] != want {
+						t.Errorf("luminosity %d at (%d, %d) mapped to %d, want %d", lum, x, y, got[y][x], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCLAHEFilterEqualizesTilesSeparately(t *testing.T) {
+	// A dark band on the left and a bright band on the right. Global equalization would only spread each band over half the range
+	rows := make([][]int, 4)
+	for y := range rows {
+		rows[y] = append(bandRows(16, 1, 10, 4)[0], bandRows(16, 1, 200, 4)[0]...)
+	}
+
+	got := applyFilter(CLAHEFilter(2, 1, 0), rows)
+
+	// Outside the centres of the outer tiles, the mapping is the tile's own equalization rather than a blend
+	for _, x := range []int{0, 28} {
+		if got[0][x] != 0 || got[0][x + 3] != 255 {
+			t.Errorf("tile at column %d spans %d-%d, want 0-255", x, got[0][x], got[0][x + 3])
+		}
+	}
+}
+
+func TestCLAHEFilterSingleTileMatchesGlobal(t *testing.T) {
+	rows := bandRows(9, 5, 40, 7)
+	rows[2][3] = 250
+
+	got := applyFilter(CLAHEFilter(1, 1, 0), rows)
+	want := applyFilter(HistogramEqualizationFilter(), rows)
+
+	if !equalRows(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCLAHEFilterClipLimitsContrast(t *testing.T) {
+	rows := bandRows(16, 16, 120, 2)
+
+	unclipped := applyFilter(CLAHEFilter(1, 1, 0), rows)
+	clipped := applyFilter(CLAHEFilter(1, 1, 1), rows)
+
+	unclippedSpread := unclipped[0][1] - unclipped[0][0]
+	clippedSpread := clipped[0][1] - clipped[0][0]
+	if clippedSpread >= unclippedSpread {
+		t.Errorf("spread with clip limit 1 = %d, want less than %d without clipping", clippedSpread, unclippedSpread)
+	}
+	if clippedSpread > 4 {
+		t.Errorf("spread with clip limit 1 = %d, want roughly the original spread of 1", clippedSpread)
+	}
+}
+
+func TestCLAHEFilterClampsTiles(t *testing.T) {
+	rows := bandRows(3, 2, 0, 3)
+
+	// More tiles than characters should behave like one tile per character, not panic
+	got := applyFilter(CLAHEFilter(100, 100, 2), rows)
+	if len(got) != 2 || len(got[0]) != 3 {
+		t.Fatalf("got a %dx%d grid, want 3x2", len(got[0]), len(got))
+	}
+}
+
+func TestClipHistogram(t *testing.T) {
+	var hist [256]int
+	hist[10] = 1000
+	hist[20] = 30
+
+	clipHistogram(&hist, 100)
+
+	total := 0
+	for _, count := range hist {
+		total += count
+	}
+	if total != 1030 {
+		t.Errorf("total after clipping = %d, want 1030", total)
+	}
+
+	// 900 clipped from bin 10 is spread as 3 per bin, plus 1 for the first 132 bins
+	if hist[10] != 104 || hist[20] != 34 || hist[255] != 3 {
+		t.Errorf("bins 10, 20, 255 = %d, %d, %d, want 104, 34, 3", hist[10], hist[20], hist[255])
+	}
+}
+
+func TestLuminosityFiltersApplyToLuminosity(t *testing.T) {
+	img := makeTestLuminosity(bandRows(8, 2, 100, 4))
+
+	got := New(WithLuminosityFilters(HistogramEqualizationFilter())).MapLuminosity(img)
+	if lo, hi := got.LuminosityAt(0, 0), got.LuminosityAt(3, 0); lo != 0 || hi != 255 {
+		t.Errorf("equalized band spans %d-%d, want 0-255", lo, hi)
+	}
+}
@@ -0,0 +1,208 @@
+package asciiart
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+/*
+FrameEncoder writes a sequence of canvases to a terminal as ANSI output, drawing every canvas over the previous one. Only the first canvas is drawn in full. Every later canvas is written as a diff against the previous canvas (see WriteFrameDiff()), so animations stream at a fraction of the bytes of redrawing every frame, e.g. over SSH.
+
+The canvas is drawn with its top left corner at the top left of the screen.
+*/
+type FrameEncoder struct {
+	w			io.Writer
+	prev		*Canvas
+}
+
+/*
+NewFrameEncoder returns a FrameEncoder writing to w.
+*/
+func NewFrameEncoder(w io.Writer) *FrameEncoder {
+	return &FrameEncoder {
+		w: w,
+	}
+}
+
+/*
+Encode writes canvas over the canvas encoded before it. The screen is cleared and canvas is drawn in full if it is the first canvas (or the first since Reset()), or if its size differs from the previous canvas.
+*/
+func (e *FrameEncoder) Encode(canvas *Canvas) error {
+	if err := WriteFrameDiff(e.w, e.prev, canvas); err != nil {
+		return err
+	}
+
+	e.prev = canvas
+	return nil
+}
+
+/*
+Reset forgets the previous canvas, so the next canvas is drawn in full. Use it when the screen may no longer show the previous canvas, e.g. when a new client connects to a stream.
+*/
+func (e *FrameEncoder) Reset() {
+	e.prev = nil
+}
+
+/*
+WriteFrameDiff writes the ANSI output that turns prev, which is already on screen, into next. Only the runs of cells that changed are written, with cursor movement in between, and styles are only written when they change from the last written cell. Cells are compared by what is drawn (the character and its style), so changes to fields that are not drawn, e.g. the true colour Cell.FG, write nothing. Short gaps of unchanged cells between changed cells are rewritten when that takes no more bytes (including the style changes) than moving the cursor over them. If prev is nil or its size differs from next, the screen is cleared and next is drawn in full.
+
+All styles are reset at the end of the output, and the cursor is left at the start of the line below the canvas, so anything written afterwards is not affected by the diff. Nothing is written if no cell changed.
+*/
+func WriteFrameDiff(w io.Writer, prev, next *Canvas) error {
+	bw := bufio.NewWriter(w)
+	d := frameDiffWriter {
+		w: bw,
+		sgr: newSGRWriter(bw),
+		// Start with the cursor at an unknown position, so the first run always moves the cursor
+		cursorX: -1,
+		cursorY: -1,
+	}
+
+	full := prev == nil || prev.Width != next.Width || prev.Height != next.Height
+	if full {
+		bw.WriteString("\x1b[0m\x1b[2J")
+	}
+
+	changed := func(x, y int) bool {
+		return full || !drawnEqual(next.Cells[y][x], prev.Cells[y][x])
+	}
+
+	for y, row := range next.Cells {
+		for x := 0; x < len(row); {
+			if !changed(x, y) {
+				x++
+				continue
+			}
+
+			d.moveTo(x, y)
+
+			// Keep writing through short gaps of unchanged cells, when rewriting them takes no more bytes than moving the cursor over them
+			for x < len(row) {
+				if changed(x, y) {
+					d.writeCell(row[x])
+					x++
+					continue
+				}
+
+				gap := 0
+				for x + gap < len(row) && !changed(x + gap, y) {
+					gap++
+				}
+				if x + gap == len(row) || d.gapRewriteCost(row[x : x + gap + 1]) > d.gapMoveCost(gap, row[x + gap]) {
+					break
+				}
+
+				for range gap {
+					d.writeCell(row[x])
+					x++
+				}
+			}
+		}
+	}
+
+	// Nothing changed, so the screen and cursor are already where the previous diff left them
+	if d.cursorY == -1 {
+		return bw.Flush()
+	}
+
+	// Reset all styles and leave the cursor below the canvas
+	d.sgr.reset()
+	d.moveTo(0, next.Height)
+
+	return bw.Flush()
+}
+
+// frameDiffWriter tracks the cursor position and styles of the terminal while writing a frame diff
+type frameDiffWriter struct {
+	w				*bufio.Writer
+	sgr				*sgrWriter
+	cursorX			int
+	cursorY			int
+}
+
+// moveTo moves the cursor to cell (x, y), using the shortest sequence
+func (d *frameDiffWriter) moveTo(x, y int) {
+	switch {
+		case x == d.cursorX && y == d.cursorY:
+			// Already there
+		case y == d.cursorY && x > d.cursorX:
+			d.w.WriteString(cursorForward(x - d.cursorX))
+		default:
+			// Cursor positions are 1 indexed
+			d.w.WriteString("\x1b[")
+			d.w.WriteString(strconv.Itoa(y + 1))
+			d.w.WriteByte(';')
+			d.w.WriteString(strconv.Itoa(x + 1))
+			d.w.WriteByte('H')
+	}
+
+	d.cursorX, d.cursorY = x, y
+}
+
+/*
+gapRewriteCost returns how many bytes rewriting the unchanged cells of a gap takes, followed by the style change to the changed cell after them. cells is the gap followed by that changed cell.
+*/
+func (d *frameDiffWriter) gapRewriteCost(cells []Cell) int {
+	cost := 0
+	style := d.sgr.style
+	for i, cell := range cells {
+		next := drawnStyle(cell, style)
+		cost += d.sgr.cost(style, next)
+		style = next
+
+		if i < len(cells) - 1 {
+			cost += cellRuneLen(cell)
+		}
+	}
+	return cost
+}
+
+// gapMoveCost returns how many bytes moving the cursor over a gap of n cells takes, followed by the style change to the changed cell after it
+func (d *frameDiffWriter) gapMoveCost(n int, next Cell) int {
+	return len(cursorForward(n)) + d.sgr.cost(d.sgr.style, drawnStyle(next, d.sgr.style))
+}
+
+// cellRuneLen returns how many bytes writeCell() writes for the character of cell
+func cellRuneLen(cell Cell) int {
+	if cell.Transparent {
+		return 1
+	}
+
+	// Invalid runes are written as utf8.RuneError
+	if n := utf8.RuneLen(cell.Rune); n > 0 {
+		return n
+	}
+	return utf8.RuneLen(utf8.RuneError)
+}
+
+/*
+drawnEqual reports whether a and b look the same on screen. Only the fields that are drawn are compared, so e.g. a change of the true colour FG that maps onto the same escape sequence does not redraw the cell.
+*/
+func drawnEqual(a, b Cell) bool {
+	if a.Transparent || b.Transparent {
+		return a.Transparent == b.Transparent
+	}
+	return a.Rune == b.Rune && cellStyle(a) == cellStyle(b)
+}
+
+// writeCell writes cell at the cursor, changing its style first if needed. Transparent cells are written as a space without a background, as ANSIRenderer() does
+func (d *frameDiffWriter) writeCell(cell Cell) {
+	d.sgr.set(drawnStyle(cell, d.sgr.style))
+	if cell.Transparent {
+		d.w.WriteRune(' ')
+	} else {
+		d.w.WriteRune(cell.Rune)
+	}
+
+	d.cursorX++
+}
+
+// cursorForward returns the escape sequence that moves the cursor n cells to the right
+func cursorForward(n int) string {
+	if n == 1 {
+		return "\x1b[C"
+	}
+	return "\x1b[" + strconv.Itoa(n) + "C"
+}
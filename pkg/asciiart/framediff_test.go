@@ -0,0 +1,286 @@
+package asciiart
+
+import (
+	"bytes"
+	"image/color"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// plainRow returns a row of uncoloured cells, one per rune of s
+func plainRow(s string) []Cell {
+	row := make([]Cell, 0, len(s))
+	for _, r := range s {
+		row = append(row, Cell{Rune: r})
+	}
+	return row
+}
+
+// withCell returns a copy of canvas with the cell at (x, y) replaced
+func withCell(canvas *Canvas, x, y int, cell Cell) *Canvas {
+	rows := make([][]Cell, len(canvas.Cells))
+	for i, row := range canvas.Cells {
+		rows[i] = append([]Cell(nil), row...)
+	}
+	rows[y][x] = cell
+	return testCanvas(rows...)
+}
+
+func frameDiff(t *testing.T, prev, next *Canvas) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := WriteFrameDiff(&buf, prev, next); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestWriteFrameDiff(t *testing.T) {
+	tests := []struct {
+		name	string
+		prev	*Canvas
+		next	*Canvas
+		want	string
+	}{
+		{
+			name: "first frame is drawn in full",
+			next: styledCanvas(),
+			want: "\x1b[0m\x1b[2J\x1b[1;1H\x1b[31mab\x1b[32;1mc\x1b[2;1H\x1b[0md \x1b[32me\x1b[0m\x1b[3;1H",
+		},
+		{
+			name: "no change writes nothing",
+			prev: styledCanvas(),
+			next: styledCanvas(),
+			want: "",
+		},
+		{
+			name: "single changed cell",
+			prev: styledCanvas(),
+			next: withCell(styledCanvas(), 1, 1, Cell{Rune: 'x'}),
+			want: "\x1b[2;2Hx\x1b[3;1H",
+		},
+		{
+			name: "changed cell is written in its style",
+			prev: styledCanvas(),
+			next: withCell(styledCanvas(), 2, 1, Cell{Rune: 'f', ColorEscape: "\x1b[32m"}),
+			want: "\x1b[2;3H\x1b[32mf\x1b[0m\x1b[3;1H",
+		},
+		{
+			name: "style only change is written",
+			prev: styledCanvas(),
+			next: withCell(styledCanvas(), 0, 1, Cell{Rune: 'd', Bold: true}),
+			want: "\x1b[2;1H\x1b[1md\x1b[0m\x1b[3;1H",
+		},
+		{
+			// Rewriting a gap of 4 takes as many bytes as \x1b[4C, so it is rewritten
+			name: "short gap is rewritten",
+			prev: testCanvas(plainRow("abcdefgh")),
+			next: testCanvas(plainRow("XbcdeYgh")),
+			want: "\x1b[1;1HXbcdeY\x1b[2;1H",
+		},
+		{
+			// A gap of 5 takes more bytes than \x1b[5C, so the cursor is moved
+			name: "long gap moves the cursor",
+			prev: testCanvas(plainRow("abcdefgh")),
+			next: testCanvas(plainRow("XbcdefYh")),
+			want: "\x1b[1;1HX\x1b[5CY\x1b[2;1H",
+		},
+		{
+			name: "long gap on a wide row",
+			prev: testCanvas(plainRow("abcdefghijklmnop")),
+			next: testCanvas(plainRow("XbcdefghijklmnYp")),
+			want: "\x1b[1;1HX\x1b[13CY\x1b[2;1H",
+		},
+		{
+			name: "unchanged end of line is not written",
+			prev: testCanvas(plainRow("abcd"), plainRow("efgh")),
+			next: testCanvas(plainRow("aXcd"), plainRow("Yfgh")),
+			want: "\x1b[1;2HX\x1b[2;1HY\x1b[3;1H",
+		},
+		{
+			name: "fields that are not drawn are ignored",
+			prev: styledCanvas(),
+			next: withCell(styledCanvas(), 0, 0, Cell{Rune: 'a', ColorEscape: "\x1b[31m", FG: color.RGBA{200, 10, 10, 255}, ColorCode: 9, IsEdge: true}),
+			want: "",
+		},
+		{
+			name: "transparent cells with different runes are equal",
+			prev: styledCanvas(),
+			next: withCell(styledCanvas(), 1, 1, Cell{Rune: 'x', Transparent: true}),
+			want: "",
+		},
+		{
+			// Rewriting "bc" would also take a reset before them and the red again after them, 11 bytes instead of the 4 of \x1b[2C
+			name: "style changes count towards rewriting a gap",
+			prev: testCanvas(plainRow("abcdefgh")),
+			next: testCanvas([]Cell{{Rune: 'X', ColorEscape: "\x1b[31m"}, {Rune: 'b'}, {Rune: 'c'}, {Rune: 'Y', ColorEscape: "\x1b[31m"}, {Rune: 'e'}, {Rune: 'f'}, {Rune: 'g'}, {Rune: 'h'}}),
+			want: "\x1b[1;1H\x1b[31mX\x1b[2CY\x1b[0m\x1b[2;1H",
+		},
+		{
+			name: "a gap in the same style is rewritten",
+			prev: testCanvas(plainRow("abcdefgh")),
+			next: testCanvas([]Cell{{Rune: 'X', ColorEscape: "\x1b[31m"}, {Rune: 'b'}, {Rune: 'c'}, {Rune: 'Y'}, {Rune: 'e'}, {Rune: 'f'}, {Rune: 'g'}, {Rune: 'h'}}),
+			want: "\x1b[1;1H\x1b[31mX\x1b[0mbcY\x1b[2;1H",
+		},
+		{
+			// Each block is 3 bytes, so the 2 block gap takes more to rewrite than \x1b[2C
+			name: "multibyte runes count towards rewriting a gap",
+			prev: testCanvas(plainRow("a██b")),
+			next: testCanvas(plainRow("X██Y")),
+			want: "\x1b[1;1HX\x1b[2CY\x1b[2;1H",
+		},
+		{
+			name: "size change redraws in full",
+			prev: testCanvas(plainRow("ab")),
+			next: testCanvas(plainRow("ab"), plainRow("cd")),
+			want: "\x1b[0m\x1b[2J\x1b[1;1Hab\x1b[2;1Hcd\x1b[3;1H",
+		},
+		{
+			name: "width change redraws in full",
+			prev: testCanvas(plainRow("ab")),
+			next: testCanvas(plainRow("abc")),
+			want: "\x1b[0m\x1b[2J\x1b[1;1Habc\x1b[2;1H",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := frameDiff(t, tt.prev, tt.next); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorForward(t *testing.T) {
+	for n, want := range map[int]string{1: "\x1b[C", 2: "\x1b[2C", 12: "\x1b[12C"} {
+		if got := cursorForward(n); got != want {
+			t.Errorf("cursorForward(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestFrameEncoder(t *testing.T) {
+	first := styledCanvas()
+	second := withCell(first, 1, 1, Cell{Rune: 'x'})
+
+	var buf bytes.Buffer
+	enc := NewFrameEncoder(&buf)
+
+	encode := func(canvas *Canvas) string {
+		t.Helper()
+
+		buf.Reset()
+		if err := enc.Encode(canvas); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	if got, want := encode(first), frameDiff(t, nil, first); got != want {
+		t.Errorf("first frame = %q, want a full draw %q", got, want)
+	}
+	if got, want := encode(second), frameDiff(t, first, second); got != want {
+		t.Errorf("second frame = %q, want the diff %q", got, want)
+	}
+	if got := encode(second); got != "" {
+		t.Errorf("unchanged frame = %q, want nothing", got)
+	}
+
+	enc.Reset()
+	if got, want := encode(second), frameDiff(t, nil, second); got != want {
+		t.Errorf("frame after Reset() = %q, want a full draw %q", got, want)
+	}
+}
+
+// screen is a minimal terminal, tracking the runes on screen and the cursor. It understands the sequences WriteFrameDiff() writes, and ignores styles
+type screen struct {
+	cells	[][]rune
+	x, y	int
+}
+
+func newScreen(width, height int) *screen {
+	s := &screen{cells: make([][]rune, height + 1)}
+	for y := range s.cells {
+		s.cells[y] = []rune(strings.Repeat(" ", width))
+	}
+	return s
+}
+
+func (s *screen) write(t *testing.T, out string) {
+	t.Helper()
+
+	for len(out) > 0 {
+		rest, ok := strings.CutPrefix(out, "\x1b[")
+		if !ok {
+			r := []rune(out)[0]
+			s.cells[s.y][s.x] = r
+			s.x++
+			out = out[len(string(r)):]
+			continue
+		}
+
+		end := strings.IndexAny(rest, "mHCJ")
+		params, cmd := rest[:end], rest[end]
+		out = rest[end + 1:]
+
+		switch cmd {
+			case 'H':
+				y, x, _ := strings.Cut(params, ";")
+				row, _ := strconv.Atoi(y)
+				col, _ := strconv.Atoi(x)
+				s.x, s.y = col - 1, row - 1
+			case 'C':
+				n := 1
+				if params != "" {
+					n, _ = strconv.Atoi(params)
+				}
+				s.x += n
+			case 'J':
+				for y := range s.cells {
+					s.cells[y] = []rune(strings.Repeat(" ", len(s.cells[y])))
+				}
+		}
+	}
+}
+
+func (s *screen) rows(height int) []string {
+	rows := make([]string, height)
+	for y := range height {
+		rows[y] = string(s.cells[y])
+	}
+	return rows
+}
+
+func TestFrameDiffsReproduceFrames(t *testing.T) {
+	frames := [][]string{
+		{"abcdefghij", "klmnopqrst"},
+		{"abcdefghij", "klmnopqrst"},
+		{"aXcdefghiY", "klmnopqrst"},
+		{"aXcdefghiY", "Zlm  opqrs"},
+		{"0123456789", "          "},
+		{"0123456789", "░█ ░█ ░█ ░"},
+	}
+
+	scr := newScreen(10, 2)
+	var buf bytes.Buffer
+	enc := NewFrameEncoder(&buf)
+
+	for i, frame := range frames {
+		buf.Reset()
+		if err := enc.Encode(testCanvas(plainRow(frame[0]), plainRow(frame[1]))); err != nil {
+			t.Fatal(err)
+		}
+		scr.write(t, buf.String())
+
+		got := scr.rows(2)
+		if got[0] != frame[0] || got[1] != frame[1] {
+			t.Errorf("frame %d: screen shows %q, want %q", i, got, frame)
+		}
+		if buf.Len() > 0 && (scr.x != 0 || scr.y != 2) {
+			t.Errorf("frame %d: cursor left at (%d, %d), want (0, 2)", i, scr.x, scr.y)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package asciiart
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// defaultHTMLClassPrefix is the class prefix used by HTMLRenderer() when HTMLRendererOptions.ClassPrefix is empty
+const defaultHTMLClassPrefix = "aa"
+
+/*
+HTMLRendererOptions represents the configuration of the HTML renderer. The zero value renders with inline styles.
+*/
+type HTMLRendererOptions struct {
+	// UseClasses styles the characters with classes (e.g. class="aa-ff8800 aa-b") instead of inline style attributes
+	UseClasses		bool
+	// Stylesheet writes a <style> block defining every class used, before the <pre> block. Only used with UseClasses
+	Stylesheet		bool
+	// ClassPrefix is the prefix of every class name. Defaults to "aa". It is escaped wherever it is written
+	ClassPrefix		string
+}
+
+// htmlStyle is the styling of a run of cells. Cells with equal htmlStyle are merged into a single <span>
+type htmlStyle struct {
+	fg			color.RGBA
+	bg			color.RGBA
+	hasFG		bool
+	bold		bool
+}
+
+func cellHTMLStyle(cell Cell) htmlStyle {
+	if cell.Transparent {
+		return htmlStyle{}
+	}
+
+	style := htmlStyle {
+		bg: cell.BG,
+		bold: cell.Bold,
+	}
+
+	if cell.HasColor() {
+		style.fg = cellDisplayColor(cell)
+		style.hasFG = true
+	}
+
+	return style
+}
+
+/*
+cellDisplayColor returns the colour a coloured cell is displayed with: the palette colour picked by the color mapper (see ansiEscapeColor()), so that cells mapped to the same code share a colour. Escape sequences that are not a plain colour, e.g. from a custom ANSIColorMapper, fall back to the true colour of the cell.
+*/
+func cellDisplayColor(cell Cell) color.RGBA {
+	if c, ok := ansiEscapeColor(cell.ColorEscape); ok {
+		return c
+	}
+	return cell.FG
+}
+
+// isPlain reports whether the style needs no <span> at all
+func (s htmlStyle) isPlain() bool {
+	return !s.hasFG && !s.bold && s.bg.A == 0
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// inlineCSS returns the style attribute value of s
+func (s htmlStyle) inlineCSS() string {
+	css := ""
+	if s.hasFG {
+		css += "color:#" + hexColor(s.fg) + ";"
+	}
+	if s.bg.A != 0 {
+		css += "background-color:#" + hexColor(s.bg) + ";"
+	}
+	if s.bold {
+		css += "font-weight:bold;"
+	}
+	return css
+}
+
+// classes returns the class attribute value of s
+func (s htmlStyle) classes(prefix string) string {
+	classes := ""
+	if s.hasFG {
+		classes += prefix + "-" + hexColor(s.fg)
+	}
+	if s.bg.A != 0 {
+		if classes != "" {
+			classes += " "
+		}
+		classes += prefix + "-bg-" + hexColor(s.bg)
+	}
+	if s.bold {
+		if classes != "" {
+			classes += " "
+		}
+		classes += prefix + "-b"
+	}
+	return classes
+}
+
+/*
+cssIdent escapes ident for use as a class name in a CSS selector. Characters other than letters, digits, - and _ are written as hex escapes, so a ClassPrefix cannot close the <style> block.
+*/
+func cssIdent(ident string) string {
+	var sb strings.Builder
+	for _, r := range ident {
+		if r == '-' || r == '_' || r >= 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		} else {
+			fmt.Fprintf(&sb, "\\%x ", r)
+		}
+	}
+	return sb.String()
+}
+
+/*
+writeHTMLStylesheet writes a <style> block defining every class used by canvas, in the order they first appear.
+*/
+func writeHTMLStylesheet(bw *bufio.Writer, canvas *Canvas, prefix string) {
+	seen := make(map[string]bool)
+
+	bw.WriteString("<style>\n")
+	for _, row := range canvas.Cells {
+		for _, cell := range row {
+			style := cellHTMLStyle(cell)
+
+			if style.hasFG {
+				class := prefix + "-" + hexColor(style.fg)
+				if !seen[class] {
+					seen[class] = true
+					fmt.Fprintf(bw, ".%s{color:#%s}\n", cssIdent(class), hexColor(style.fg))
+				}
+			}
+
+			if style.bg.A != 0 {
+				class := prefix + "-bg-" + hexColor(style.bg)
+				if !seen[class] {
+					seen[class] = true
+					fmt.Fprintf(bw, ".%s{background-color:#%s}\n", cssIdent(class), hexColor(style.bg))
+				}
+			}
+
+			if style.bold && !seen[prefix + "-b"] {
+				seen[prefix + "-b"] = true
+				fmt.Fprintf(bw, ".%s{font-weight:bold}\n", cssIdent(prefix + "-b"))
+			}
+		}
+	}
+	bw.WriteString("</style>\n")
+}
+
+// writeMarkupRune writes r, escaping the characters that have a meaning in HTML and XML
+func writeMarkupRune(bw *bufio.Writer, r rune) {
+	switch r {
+		case '<':
+			bw.WriteString("&lt;")
+		case '>':
+			bw.WriteString("&gt;")
+		case '&':
+			bw.WriteString("&amp;")
+		case '"':
+			bw.WriteString("&#34;")
+		case '\'':
+			bw.WriteString("&#39;")
+		default:
+			bw.WriteRune(r)
+	}
+}
+
+/*
+HTMLRenderer returns a Renderer that writes the canvas as a <pre> block. Adjacent characters with the same colour and boldness are merged into a single <span>, and characters from the ramp that have a meaning in HTML (<, >, & and quotes) are escaped.
+
+Colours are the palette colours picked by the color mapper, as displayed by xterm's default palette, so the page matches the terminal output and a class-based palette has at most one class per colour code. Only the 24 bit color mapper (or a custom one) uses the true colours of the cells. Cells without colour are left unstyled, so they use the text colour of the page.
+*/
+func HTMLRenderer(opts HTMLRendererOptions) Renderer {
+	prefix := opts.ClassPrefix
+	if prefix == "" {
+		prefix = defaultHTMLClassPrefix
+	}
+
+	return func(w io.Writer, canvas *Canvas) error {
+		bw := bufio.NewWriter(w)
+
+		if opts.UseClasses {
+			if opts.Stylesheet {
+				writeHTMLStylesheet(bw, canvas, prefix)
+			}
+			fmt.Fprintf(bw, "<pre class=\"%s\">", html.EscapeString(prefix))
+		} else {
+			bw.WriteString("<pre>")
+		}
+
+		var current htmlStyle
+		open := false
+
+		for _, row := range canvas.Cells {
+			for _, cell := range row {
+				style := cellHTMLStyle(cell)
+
+				if !open || style != current {
+					if open {
+						bw.WriteString("</span>")
+						open = false
+					}
+
+					current = style
+					if !style.isPlain() {
+						open = true
+						if opts.UseClasses {
+							fmt.Fprintf(bw, "<span class=\"%s\">", html.EscapeString(style.classes(prefix)))
+						} else {
+							fmt.Fprintf(bw, "<span style=\"%s\">", style.inlineCSS())
+						}
+					}
+				}
+
+				writeMarkupRune(bw, cell.Rune)
+			}
+			bw.WriteRune('\n')
+		}
+
+		if open {
+			bw.WriteString("</span>")
+		}
+		bw.WriteString("</pre>\n")
+
+		return bw.Flush()
+	}
+}
@@ -0,0 +1,150 @@
+package asciiart
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// renderToString renders canvas with renderer, failing the test on error
+func renderToString(t *testing.T, renderer Renderer, canvas *Canvas) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := renderer(&buf, canvas); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestHTMLRenderer(t *testing.T) {
+	tests := []struct {
+		name	string
+		opts	HTMLRendererOptions
+		canvas	*Canvas
+		want	string
+	}{
+		{
+			name: "inline styles",
+			canvas: styledCanvas(),
+			want: "<pre><span style=\"color:#cd0000;\">ab</span><span style=\"color:#00cd00;font-weight:bold;\">c\n</span>d <span style=\"color:#00cd00;\">e\n</span></pre>\n",
+		},
+		{
+			name: "classes and stylesheet",
+			opts: HTMLRendererOptions{UseClasses: true, Stylesheet: true},
+			canvas: styledCanvas(),
+			want: "<style>\n.aa-cd0000{color:#cd0000}\n.aa-00cd00{color:#00cd00}\n.aa-b{font-weight:bold}\n</style>\n" +
+				"<pre class=\"aa\"><span class=\"aa-cd0000\">ab</span><span class=\"aa-00cd00 aa-b\">c\n</span>d <span class=\"aa-00cd00\">e\n</span></pre>\n",
+		},
+		{
+			name: "markup characters are escaped",
+			canvas: testCanvas([]Cell{{Rune: '<'}, {Rune: '&'}, {Rune: '"'}, {Rune: '\''}, {Rune: '>'}}),
+			want: "<pre>&lt;&amp;&#34;&#39;&gt;\n</pre>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderToString(t, HTMLRenderer(tt.opts), tt.canvas); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLRendererMergesMappedColours(t *testing.T) {
+	// Different true colours mapped to the same code are one run, and one class
+	canvas := testCanvas([]Cell{
+		{Rune: 'a', FG: color.RGBA{200, 10, 10, 255}, ColorCode: 31, ColorEscape: "\x1b[31m"},
+		{Rune: 'b', FG: color.RGBA{180, 0, 30, 255}, ColorCode: 31, ColorEscape: "\x1b[31m"},
+		{Rune: 'c', FG: color.RGBA{250, 40, 0, 255}, ColorCode: 31, ColorEscape: "\x1b[31m"},
+	})
+
+	got := renderToString(t, HTMLRenderer(HTMLRendererOptions{UseClasses: true, Stylesheet: true}), canvas)
+	want := "<style>\n.aa-cd0000{color:#cd0000}\n</style>\n<pre class=\"aa\"><span class=\"aa-cd0000\">abc\n</span></pre>\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// A photo with the 4 bit mapper has at most one class per code
+	img := checkerboard(32, 32, color.RGBA{200, 10, 10, 255}, color.RGBA{190, 20, 30, 255})
+	for y := range 32 {
+		for x := range 32 {
+			if x > 16 {
+				img.Set(x, y, color.RGBA{uint8(100 + x), uint8(150 + y), 40, 255})
+			}
+		}
+	}
+
+	a := New(WithDefault4BitColorMapper(), WithSobel(false), WithRenderer(HTMLRenderer(HTMLRendererOptions{UseClasses: true, Stylesheet: true})))
+	out := a.Convert(img, 32, 16)
+
+	stylesheet := out[:strings.Index(out, "</style>")]
+	if classes := strings.Count(stylesheet, "{color:"); classes > 16 {
+		t.Errorf("stylesheet has %d colour classes, want at most 16", classes)
+	}
+}
+
+func TestHTMLRendererTrueColour(t *testing.T) {
+	canvas := testCanvas([]Cell{
+		{Rune: 'a', ColorEscape: "\x1b[38;2;1;2;3m"},
+		{Rune: 'b', ColorEscape: "\x1b[38;2;4;5;6m"},
+		// A custom escape that is not a colour uses the true colour of the cell
+		{Rune: 'c', FG: color.RGBA{7, 8, 9, 255}, ColorEscape: "\x1b[4;31m"},
+	})
+
+	got := renderToString(t, HTMLRenderer(HTMLRendererOptions{}), canvas)
+	want := "<pre><span style=\"color:#010203;\">a</span><span style=\"color:#040506;\">b</span><span style=\"color:#070809;\">c\n</span></pre>\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHTMLRendererEscapesClassPrefix(t *testing.T) {
+	opts := HTMLRendererOptions{UseClasses: true, Stylesheet: true, ClassPrefix: "x\"></style><script>"}
+	got := renderToString(t, HTMLRenderer(opts), styledCanvas())
+
+	if strings.Contains(got, "<script>") || strings.Count(got, "</style>") != 1 {
+		t.Fatalf("class prefix was not escaped: %q", got)
+	}
+	if !strings.Contains(got, "<pre class=\"x&#34;&gt;&lt;/style&gt;&lt;script&gt;\">") {
+		t.Errorf("pre class attribute not escaped: %q", got)
+	}
+	if !strings.Contains(got, ".x\\22 \\3e \\3c \\2f style\\3e \\3c script\\3e -cd0000{color:#cd0000}") {
+		t.Errorf("stylesheet selector not escaped: %q", got)
+	}
+}
+
+func TestANSIEscapeColor(t *testing.T) {
+	tests := []struct {
+		escape	string
+		want	color.RGBA
+		ok		bool
+	}{
+		{"\x1b[30m", color.RGBA{0, 0, 0, 255}, true},
+		{"\x1b[37m", color.RGBA{229, 229, 229, 255}, true},
+		{"\x1b[91m", color.RGBA{255, 0, 0, 255}, true},
+		{"\x1b[97m", color.RGBA{255, 255, 255, 255}, true},
+		{"\x1b[38;5;1m", color.RGBA{205, 0, 0, 255}, true},
+		{"\x1b[38;5;16m", color.RGBA{0, 0, 0, 255}, true},
+		{"\x1b[38;5;67m", color.RGBA{95, 135, 175, 255}, true},
+		{"\x1b[38;5;231m", color.RGBA{255, 255, 255, 255}, true},
+		{"\x1b[38;5;232m", color.RGBA{8, 8, 8, 255}, true},
+		{"\x1b[38;5;255m", color.RGBA{238, 238, 238, 255}, true},
+		{"\x1b[38;2;10;20;30m", color.RGBA{10, 20, 30, 255}, true},
+		{"\x1b[38;2;10;20;300m", color.RGBA{}, false},
+		{"\x1b[38;5;256m", color.RGBA{}, false},
+		{"\x1b[1m", color.RGBA{}, false},
+		{"\x1b[4;31m", color.RGBA{}, false},
+		{"\x1b[31mx", color.RGBA{}, false},
+		{"", color.RGBA{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ansiEscapeColor(tt.escape)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ansiEscapeColor(%q) = %v, %v, want %v, %v", tt.escape, got, ok, tt.want, tt.ok)
+		}
+	}
+}
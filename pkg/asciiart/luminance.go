@@ -0,0 +1,126 @@
+package asciiart
+
+import "math"
+
+// luminanceWeightScale is the fixed point scale of LuminanceModel weights. Weights of a model always sum to luminanceWeightScale
+const luminanceWeightScale = 10000
+
+// luminanceModels is the private struct that functions as a namespace for LuminanceModel values
+type luminanceModels struct { }
+
+// LuminanceModels is the public instance of luminanceModels. Do not reassign this variable
+var LuminanceModels = luminanceModels{}
+
+/*
+LuminanceModel describes how the red, green and blue channels of a colour are combined into a single luminosity (0-255). Create one with LuminanceModels, e.g. LuminanceModels.Rec601().
+
+The zero value is LuminanceModels.Rec709().
+*/
+type LuminanceModel struct {
+	// weights are the r, g, b weights in fixed point (they sum to luminanceWeightScale)
+	weights		[3]int
+	// maxChannel uses the brightest channel as the luminosity, ignoring weights
+	maxChannel	bool
+}
+
+/*
+Rec709 weights the channels as defined by ITU-R BT.709 (0.2126, 0.7152, 0.0722). This is the default, and matches sRGB displays.
+*/
+func (l luminanceModels) Rec709() LuminanceModel {
+	return LuminanceModel{weights: [3]int{2126, 7152, 722}}
+}
+
+/*
+Rec601 weights the channels as defined by ITU-R BT.601 (0.299, 0.587, 0.114). This is the model used by older (SD) video and by many image editors for greyscale conversion.
+*/
+func (l luminanceModels) Rec601() LuminanceModel {
+	return LuminanceModel{weights: [3]int{2990, 5870, 1140}}
+}
+
+/*
+Rec2020 weights the channels as defined by ITU-R BT.2020 (0.2627, 0.6780, 0.0593), for wide gamut (UHD/HDR) sources.
+*/
+func (l luminanceModels) Rec2020() LuminanceModel {
+	return LuminanceModel{weights: [3]int{2627, 6780, 593}}
+}
+
+/*
+Average weights all 3 channels equally.
+*/
+func (l luminanceModels) Average() LuminanceModel {
+	return LuminanceModel{weights: [3]int{3333, 3334, 3333}}
+}
+
+/*
+MaxChannel uses the brightest of the 3 channels as the luminosity (the V in HSV). Saturated colours come out as bright as white.
+*/
+func (l luminanceModels) MaxChannel() LuminanceModel {
+	return LuminanceModel{maxChannel: true}
+}
+
+/*
+Red uses only the red channel as the luminosity. Useful for effects, and for false colour (e.g. thermal) sources where one channel carries the signal.
+*/
+func (l luminanceModels) Red() LuminanceModel {
+	return LuminanceModel{weights: [3]int{luminanceWeightScale, 0, 0}}
+}
+
+/*
+Green uses only the green channel as the luminosity. See Red()
+*/
+func (l luminanceModels) Green() LuminanceModel {
+	return LuminanceModel{weights: [3]int{0, luminanceWeightScale, 0}}
+}
+
+/*
+Blue uses only the blue channel as the luminosity. See Red()
+*/
+func (l luminanceModels) Blue() LuminanceModel {
+	return LuminanceModel{weights: [3]int{0, 0, luminanceWeightScale}}
+}
+
+/*
+Custom weights the channels by r, g and b. The weights are normalized so they sum to 1, so only their ratio matters. Negative weights are treated as 0. If every weight is 0, Rec709() is returned instead.
+*/
+func (l luminanceModels) Custom(r, g, b float64) LuminanceModel {
+	r, g, b = max(0, r), max(0, g), max(0, b)
+	sum := r + g + b
+	if sum == 0 {
+		return l.Rec709()
+	}
+
+	wr := int(math.Round(r / sum * luminanceWeightScale))
+	wg := int(math.Round(g / sum * luminanceWeightScale))
+	// Give the rounding error to blue, so the weights always sum to exactly luminanceWeightScale
+	wb := max(0, luminanceWeightScale - wr - wg)
+
+	return LuminanceModel{weights: [3]int{wr, wg, wb}}
+}
+
+// resolvedWeights returns the weights of the model, treating the zero value as Rec709()
+func (m LuminanceModel) resolvedWeights() [3]int {
+	if m.weights == [3]int{} {
+		return LuminanceModels.Rec709().weights
+	}
+	return m.weights
+}
+
+// Luminosity8 combines 8 bit (0-255) r, g, b channels into a luminosity (0-255)
+func (m LuminanceModel) Luminosity8(r8, g8, b8 int) int {
+	if m.maxChannel {
+		return max(r8, g8, b8)
+	}
+
+	w := m.resolvedWeights()
+	return (r8 * w[0] + g8 * w[1] + b8 * w[2]) / luminanceWeightScale
+}
+
+// LuminosityLinear combines linear light (0-1) r, g, b channels into a linear light luminosity (0-1)
+func (m LuminanceModel) LuminosityLinear(r, g, b float64) float64 {
+	if m.maxChannel {
+		return max(r, g, b)
+	}
+
+	w := m.resolvedWeights()
+	return (r * float64(w[0]) + g * float64(w[1]) + b * float64(w[2])) / luminanceWeightScale
+}
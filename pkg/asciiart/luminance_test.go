@@ -0,0 +1,62 @@
+package asciiart
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLuminanceModels(t *testing.T) {
+	type rgb struct {
+		r, g, b int
+	}
+
+	tests := []struct {
+		name	string
+		model	LuminanceModel
+		// want maps a colour onto its expected luminosity
+		want	map[rgb]int
+	}{
+		{"zero value is rec709", LuminanceModel{}, map[rgb]int{{255, 0, 0}: 54, {0, 255, 0}: 182, {0, 0, 255}: 18}},
+		{"rec709", LuminanceModels.Rec709(), map[rgb]int{{255, 0, 0}: 54, {0, 255, 0}: 182, {0, 0, 255}: 18}},
+		{"rec601", LuminanceModels.Rec601(), map[rgb]int{{255, 0, 0}: 76, {0, 255, 0}: 149, {0, 0, 255}: 29}},
+		{"rec2020", LuminanceModels.Rec2020(), map[rgb]int{{255, 0, 0}: 66, {0, 255, 0}: 172, {0, 0, 255}: 15}},
+		{"average", LuminanceModels.Average(), map[rgb]int{{255, 0, 0}: 84, {0, 255, 0}: 85, {30, 60, 90}: 60}},
+		{"max channel", LuminanceModels.MaxChannel(), map[rgb]int{{255, 0, 0}: 255, {30, 60, 90}: 90}},
+		{"red", LuminanceModels.Red(), map[rgb]int{{200, 10, 10}: 200, {0, 255, 255}: 0}},
+		{"green", LuminanceModels.Green(), map[rgb]int{{10, 200, 10}: 200, {255, 0, 255}: 0}},
+		{"blue", LuminanceModels.Blue(), map[rgb]int{{10, 10, 200}: 200, {255, 255, 0}: 0}},
+		{"custom normalizes weights", LuminanceModels.Custom(2, 2, 0), map[rgb]int{{200, 100, 255}: 150}},
+		{"custom ignores negative weights", LuminanceModels.Custom(-5, 1, 0), map[rgb]int{{255, 100, 0}: 100}},
+		{"custom all zero is rec709", LuminanceModels.Custom(0, 0, 0), map[rgb]int{{255, 0, 0}: 54}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for c, want := range tt.want {
+				if got := tt.model.Luminosity8(c.r, c.g, c.b); got != want {
+					t.Errorf("Luminosity8(%d, %d, %d) = %d, want %d", c.r, c.g, c.b, got, want)
+				}
+			}
+
+			// White is always full luminosity, and black always 0
+			if got := tt.model.Luminosity8(255, 255, 255); got != 255 {
+				t.Errorf("Luminosity8(white) = %d, want 255", got)
+			}
+			if got := tt.model.LuminosityLinear(1, 1, 1); math.Abs(got - 1) > 1e-9 {
+				t.Errorf("LuminosityLinear(white) = %v, want 1", got)
+			}
+			if got := tt.model.Luminosity8(0, 0, 0); got != 0 {
+				t.Errorf("Luminosity8(black) = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestCustomLuminanceModelWeightsSumToScale(t *testing.T) {
+	for _, weights := range [][3]float64{{1, 1, 1}, {0.299, 0.587, 0.114}, {1, 2, 3}, {0.1, 0, 0}} {
+		w := LuminanceModels.Custom(weights[0], weights[1], weights[2]).weights
+		if sum := w[0] + w[1] + w[2]; sum != luminanceWeightScale {
+			t.Errorf("Custom(%v) weights %v sum to %d, want %d", weights, w, sum, luminanceWeightScale)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package asciiart
 
+import (
+	"image/color"
+	"math"
+)
+
 // WithOutputAspectRatio specifies desired aspect_ratio of the image. This field is only used if DownscalingMode is set to DownscalingModes.WithRespectToAspectRatio()
 func WithOutputAspectRatio(ratio float64) AsciiOption {
 	return func(a *AsciiConverter) {
@@ -18,9 +23,176 @@ func WithDownscalingMode(mode DownscalingMode) AsciiOption {
 	}
 }
 
+/*
+WithAlphaPolicy specifies how transparent pixels are handled:
+	- AlphaPolicies.Multiply() (default) scales the luminosity and colour by alpha, so transparent regions are rendered like black pixels.
+	- AlphaPolicies.Composite() composites the image onto a background colour first. See WithAlphaBackground()
+	- AlphaPolicies.Transparent() emits characters below an alpha threshold as spaces with no colour codes, so sprites sit cleanly on any terminal background. See WithAlphaThreshold()
+*/
+func WithAlphaPolicy(policy AlphaPolicy) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.AlphaPolicy = policy
+	}
+}
+
+/*
+WithAlphaBackground specifies the colour that transparent pixels are composited onto. This field is only used if AlphaPolicy is set to AlphaPolicies.Composite(). A nil background is treated as black.
+*/
+func WithAlphaBackground(bg color.Color) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.AlphaBackground = bg
+	}
+}
+
+/*
+WithAlphaThreshold specifies the minimum alpha (0-255) for a character to be drawn. Characters with a lower alpha are emitted as a blank space, and every other character is drawn fully opaque. This field is only used if AlphaPolicy is set to AlphaPolicies.Transparent().
+*/
+func WithAlphaThreshold(threshold int) AsciiOption {
+	threshold = min(255, max(0, threshold))
+
+	return func(a *AsciiConverter) {
+		a.AlphaThreshold = threshold
+	}
+}
+
+/*
+WithBrightness shifts the brightness of the image before it is converted. brightness is a fraction of full brightness in [-1, 1], e.g. 0.2 brightens every channel by 20%. 0 leaves the image untouched.
+
+The adjustment is applied to the colour of every character, so both the character ramp and the color mappers see the corrected image. Brightness is applied after WithGamma() and WithContrast().
+*/
+func WithBrightness(brightness float64) AsciiOption {
+	brightness = min(1, max(-1, brightness))
+
+	return func(a *AsciiConverter) {
+		a.Brightness = brightness
+	}
+}
+
+/*
+WithContrast stretches or flattens the contrast of the image around mid grey before it is converted. contrast is in [-1, 1]: positive values increase contrast, negative values decrease it (-1 turns everything mid grey), and 0 leaves the image untouched.
+
+The adjustment is applied to the colour of every character, so both the character ramp and the color mappers see the corrected image. Contrast is applied after WithGamma() and before WithBrightness().
+*/
+func WithContrast(contrast float64) AsciiOption {
+	contrast = min(1, max(-1, contrast))
+
+	return func(a *AsciiConverter) {
+		a.Contrast = contrast
+	}
+}
+
+/*
+WithGamma applies gamma correction to the image before it is converted. Values > 1 brighten the shadows and midtones (useful for dark photos), values < 1 darken them, and 1 leaves the image untouched. Values <= 0 are treated as 1.
+
+The adjustment is applied to the colour of every character, so both the character ramp and the color mappers see the corrected image. Gamma is applied before WithContrast() and WithBrightness().
+*/
+func WithGamma(gamma float64) AsciiOption {
+	if gamma <= 0 {
+		gamma = 1
+	}
+
+	return func(a *AsciiConverter) {
+		a.Gamma = gamma
+	}
+}
+
+/*
+WithLuminosityFilters specifies the chain of filters to run over the luminosity grid (in the order given) before characters are mapped. Unlike WithPreFilters(), these filters change the luminosity seen by the luminosity mapper, the color mappers and sobel edge detection. Calling WithLuminosityFilters() with no filters disables luminosity filtering.
+
+The library provides HistogramEqualizationFilter() and CLAHEFilter() to make use of the full character ramp, for example:
+
+	asciiart.New(
+		asciiart.WithLuminosityFilters(asciiart.CLAHEFilter(8, 4, 3)),
+	)
+*/
+func WithLuminosityFilters(filters ...LuminosityFilter) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.LuminosityFilters = filters
+	}
+}
+
+/*
+WithLuminanceModel specifies how the r, g, b channels of each character are combined into luminosity. By default LuminanceModels.Rec709() is used. See LuminanceModels for the standard models, single channel models (for effects or false colour sources), and LuminanceModels.Custom() for custom weights.
+*/
+func WithLuminanceModel(model LuminanceModel) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.LuminanceModel = model
+	}
+}
+
+/*
+WithLinearLight enables/disables gamma correct processing. Image colours are sRGB encoded, so averaging or weighting them directly makes gradients and anti-aliased edges look too dark. With linear light enabled:
+	- DownscaleImage() averages every source pixel covered by a character in linear light (instead of picking the nearest pixel), then encodes the result back to sRGB.
+	- MapLuminosity() weights the channels in linear light, and encodes the luminosity back to sRGB so it follows perceived brightness.
+
+Linear light is slower than the default, since every source pixel is visited while downscaling.
+*/
+func WithLinearLight(useLinearLight bool) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.LinearLight = useLinearLight
+	}
+}
+
+/*
+WithRenderer specifies the Renderer that turns the converted Canvas into the output of Convert(), ConvertBytes() and ConvertReader(). The default is nil, which writes ANSI text as ANSIRenderer() does, configured with WithANSIRendererOptions(). Leave it nil for ANSI output, so ConvertTo() can write every row as soon as it is generated instead of building the Canvas.
+*/
+func WithRenderer(renderer Renderer) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.Renderer = renderer
+	}
+}
+
+/*
+WithANSIRendererOptions specifies the options of the ANSI output written when no Renderer is set (see WithRenderer()).
+*/
+func WithANSIRendererOptions(opts ANSIRendererOptions) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.ANSIRendererOptions = opts
+	}
+}
+
+/*
+WithCellBackgrounds enables/disables filling Cell.BG with the colour of the pixel each character was sampled from, so renderers that draw cell backgrounds (ANSIRenderer(), HTMLRenderer(), SVGRenderer() with DrawCellBackgrounds, RenderImage()) draw the image as a mosaic behind the characters. Disabled by default. TextRenderer() ignores cell backgrounds.
+
+Since the characters are drawn in the same colour as their background with a color mapper, cell backgrounds are best used without one (see WithNoColorMapper()), so the characters use the text colour of the renderer.
+*/
+func WithCellBackgrounds(enabled bool) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.CellBackgrounds = enabled
+	}
+}
+
+/*
+WithParallelism specifies how many goroutines each stage of the pipeline (downscaling, luminosity, sobel and character generation) is split over. Every stage is split into bands of rows, and the output is identical to converting on a single goroutine. n <= 0 uses runtime.GOMAXPROCS(0). The default is 1, which converts on the calling goroutine only.
+
+Parallelism is opt in, since with more than 1 goroutine, custom mappers (see WithLuminosityMapper(), WithEdgeMapperFactory() and WithColorMapper()) are called concurrently, so they must be safe for concurrent use. LuminosityFilters and PreFilters are always called on a single goroutine.
+*/
+func WithParallelism(n int) AsciiOption {
+	n = max(0, n)
+
+	return func(a *AsciiConverter) {
+		a.Parallelism = n
+	}
+}
+
+/*
+WithTerminalBackground specifies the background colour of the terminal the output is displayed on (TerminalBackgrounds.Dark() or TerminalBackgrounds.Light()). On a light background the character ramp is inverted, so dark pixels are drawn with dense characters, and the black/white clamps of the library 3 bit, 4 bit and 8 bit color mappers are mirrored. The 24 bit color mapper is unaffected.
+
+The library color mappers are rebuilt for the background, so this option can be given before or after the color mapper option. Custom color mappers are not adjusted. See ParseCOLORFGBG() to detect the background from the environment.
+*/
+func WithTerminalBackground(bg TerminalBackground) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.TerminalBackground = bg
+		if a.colorMapperFactory != nil {
+			a.setColorMapperFactory(a.colorMapperFactory)
+		}
+	}
+}
+
 func WithNoColorMapper() AsciiOption {
 	return func(a *AsciiConverter) {
 		a.ANSIColorMapper = NoColorMapper
+		a.colorMapperFactory = nil
 	}
 }
 
@@ -33,6 +205,52 @@ func WithSobel(useSobel bool) AsciiOption {
 	}
 }
 
+/*
+WithPreFilters specifies the chain of filters to run over the luminosity grid before sobel edge detection, in the order given. Pre-filters only affect edge detection - characters chosen by the luminosity mapper still use the unfiltered luminosity. Calling WithPreFilters() with no filters disables pre-filtering.
+
+The library provides GaussianBlurFilter(), MedianFilter() and BilateralFilter(), for example:
+
+	asciiart.New(
+		asciiart.WithSobel(true),
+		asciiart.WithPreFilters(
+			asciiart.MedianFilter(1),
+			asciiart.GaussianBlurFilter(0.8),
+		),
+	)
+*/
+func WithPreFilters(filters ...LuminosityFilter) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.PreFilters = filters
+	}
+}
+
+/*
+WithEdgeColorMode specifies which channels sobel edge detection runs on. By default (EdgeColorModes.Luminosity()) only the luminosity is used, so boundaries between two colours of the same luminosity produce no edge. Use EdgeColorModes.RGB() or EdgeColorModes.Lab() to outline isoluminant colour boundaries as well.
+*/
+func WithEdgeColorMode(mode EdgeColorMode) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.EdgeColorMode = mode
+	}
+}
+
+/*
+WithEdgeSupersampling runs sobel edge detection on an intermediate image with factor samples per character along each axis, then pools the edge strength and dominant orientation back into each character (see ApplySobelSupersampled()). This picks up fine detail that is lost when detecting edges on the downscaled image, and stops edges from aliasing. The factor is clamped to the resolution of the source image, so use WithSourceResolutionEdges() to always detect edges at full resolution.
+
+A factor <= 1 disables supersampling (the default). Note that pre-filters (see WithPreFilters()) are applied to the intermediate image, so their radius and sigma are measured in samples rather than characters.
+*/
+func WithEdgeSupersampling(factor int) AsciiOption {
+	return func(a *AsciiConverter) {
+		a.EdgeSupersampling = factor
+	}
+}
+
+/*
+WithSourceResolutionEdges runs sobel edge detection on the source image at its full resolution. See WithEdgeSupersampling()
+*/
+func WithSourceResolutionEdges() AsciiOption {
+	return WithEdgeSupersampling(math.MaxInt32)
+}
+
 /*
 WithLuminosityMapper specifies a luminosity mapper to use. A luminosity mapper maps a luminosity value (0-255) onto some character. It does not interpret the color (see WithColorMapper), it only provides the character that should be used for a normal character.
 */
@@ -79,6 +297,7 @@ func WithColorMapper(
 ) AsciiOption {
 	return func(a *AsciiConverter) {
 		a.ANSIColorMapper = colorMapper
+		a.colorMapperFactory = nil
 	}
 }
 
@@ -94,7 +313,9 @@ WithDefault3BitColorMapper sets the ascii converter to use the default configura
 */
 func WithDefault3BitColorMapper() AsciiOption {
 	return func(a *AsciiConverter) {
-		a.ANSIColorMapper = Default3BitColorMapper()
+		a.setColorMapperFactory(func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+			return default3BitColorMapperFactory(default3BitOpts, bg)
+		})
 		a.BytesPerCharToReserve = bytesPerCharReserve
 		a.AdditionalBytesPerCharColor = ansiAdditionalBytesReserved3Bit
 	}
@@ -105,7 +326,9 @@ WithDefault4BitColorMapper sets the ascii converter to use the default configura
 */
 func WithDefault4BitColorMapper() AsciiOption {
 	return func(a *AsciiConverter) {
-		a.ANSIColorMapper = Default4BitColorMapper()
+		a.setColorMapperFactory(func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+			return default4BitColorMapperFactory(default4BitOpts, bg)
+		})
 		a.BytesPerCharToReserve = bytesPerCharReserve
 		a.AdditionalBytesPerCharColor = ansiAdditionalBytesReserved4Bit
 	}
@@ -116,7 +339,9 @@ WithDefault8BitColorMapper sets the ascii converter to use the default configura
 */
 func WithDefault8BitColorMapper() AsciiOption {
 	return func(a *AsciiConverter) {
-		a.ANSIColorMapper = Default8BitColorMapper()
+		a.setColorMapperFactory(func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+			return default8BitColorMapperFactory(default8BitOpts, bg)
+		})
 		a.BytesPerCharToReserve = bytesPerCharReserve
 		a.AdditionalBytesPerCharColor = ansiAdditionalBytesReserved8Bit
 	}
@@ -128,6 +353,7 @@ WithDefault24BitColorMapper sets the ascii converter to use the default configur
 func WithDefault24BitColorMapper() AsciiOption {
 	return func(a *AsciiConverter) {
 		a.ANSIColorMapper = Default24BitColorMapper()
+		a.colorMapperFactory = nil
 		a.BytesPerCharToReserve = bytesPerCharReserve
 		a.AdditionalBytesPerCharColor = ansiAdditionalBytesReserved24Bit
 	}
@@ -141,7 +367,9 @@ func With3BitColorMapper(opts ColorMapper3BitOptions, bytesPerCharToReserve, col
 		a.BytesPerCharToReserve = bytesPerCharToReserve
 		a.AdditionalBytesPerCharColor = colorBytesPerCharToReserve
 
-		a.ANSIColorMapper = default3BitColorMapperFactory(opts)
+		a.setColorMapperFactory(func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+			return default3BitColorMapperFactory(opts, bg)
+		})
 	}
 }
 
@@ -152,7 +380,9 @@ func With4BitColorMapper(opts ColorMapper4BitOptions, bytesPerCharToReserve, col
 	return func(a *AsciiConverter) {
 		a.BytesPerCharToReserve = bytesPerCharToReserve
 		a.AdditionalBytesPerCharColor = colorBytesPerCharToReserve
-		a.ANSIColorMapper = default4BitColorMapperFactory(opts)
+		a.setColorMapperFactory(func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+			return default4BitColorMapperFactory(opts, bg)
+		})
 	}
 }
 
@@ -163,7 +393,9 @@ func With8BitColorMapper(opts ColorMapper8BitOptions, bytesPerCharToReserve, col
 	return func(a *AsciiConverter) {
 		a.BytesPerCharToReserve = bytesPerCharToReserve
 		a.AdditionalBytesPerCharColor = colorBytesPerCharToReserve
-		a.ANSIColorMapper = default8BitColorMapperFactory(opts)
+		a.setColorMapperFactory(func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string) {
+			return default8BitColorMapperFactory(opts, bg)
+		})
 	}
 }
 
@@ -175,6 +407,7 @@ func With24BitColorMapper(bytesPerCharToReserve, colorBytesPerCharToReserve floa
 		a.BytesPerCharToReserve = bytesPerCharToReserve
 		a.AdditionalBytesPerCharColor = colorBytesPerCharToReserve
 		a.ANSIColorMapper = default24BitColorMapperFactory()
+		a.colorMapperFactory = nil
 	}
 }
 
@@ -192,11 +425,16 @@ func WithSobelMagSquaredThresholdNormalized(mag2 float64) AsciiOption {
 }
 
 /*
-WithSobelLaplacianThresholdNormalized sets the field SobelLaplacianThresholdNormalized which is the maximum laplacian value from the sobel kernel before account for aspect ratio, for which a charcter is considered an edge.
+WithSobelLaplacianThresholdNormalized sets the field SobelLaplacianThresholdNormalized which is the maximum absolute laplacian value, for which a charcter is considered an edge. The laplacian is already normalized for the cell aspect ratio (see ApplySobel()), so the threshold is measured in luminosity:
+	- Flat areas have a laplacian of 0, and always pass.
+	- A luminosity step of d has a laplacian of +-d either side of a vertical edge, and +-d/aspect_ratio^2 either side of a horizontal edge.
+	- A 1 pixel wide line with a contrast of d has a laplacian of 2d along the line, and d either side of it.
+
+Since luminosity is between 0 and 255, no laplacian is larger than 510, so any threshold of 510 or more passes every character. The default of 128 drops the doubled outlines either side of hard, high contrast edges, while keeping the outlines of softer edges.
 
 If a character is considered an edge, then its ASCII character given will be determined byt he edge mapper instead of the luminosity mapper
 
-It is recommended to use a value between 100-400, with the higher the value increasing the number of edges detected.
+It is recommended to use a value between 64-255, with the higher the value increasing the number of edges detected.
 */
 func WithSobelLaplacianThresholdNormalized(lMag float64) AsciiOption {
 	return func(a *AsciiConverter) {
@@ -211,6 +449,9 @@ func WithBoldedSobelOutline(makeOutlinesBold bool) AsciiOption {
 	}
 }
 
+/*
+WithByteReserve specifies the amount of bytes per character to reserve in the result buffer of Convert(), ConvertBytes() and ConvertReader(), so the buffer does not need to grow while rendering. It is not needed with ConvertTo(), which writes the output straight to its writer.
+*/
 func WithByteReserve(bytesPerCharToReserve float64) AsciiOption {
 	if bytesPerCharToReserve <= 0 {
 		bytesPerCharToReserve = 3.5
@@ -221,6 +462,9 @@ func WithByteReserve(bytesPerCharToReserve float64) AsciiOption {
 	}
 }
 
+/*
+WithColorBytesReserve specifies the amount of additional bytes per character to reserve for colour escape sequences in the result buffer of Convert(), ConvertBytes() and ConvertReader(). It is not needed with ConvertTo(), which writes the output straight to its writer.
+*/
 func WithColorBytesReserve(additionalBytesPerCharToReserve float64) AsciiOption {
 	if additionalBytesPerCharToReserve < 0 {
 		additionalBytesPerCharToReserve = 0
@@ -230,3 +474,10 @@ func WithColorBytesReserve(additionalBytesPerCharToReserve float64) AsciiOption
 		a.AdditionalBytesPerCharColor = additionalBytesPerCharToReserve
 	}
 }
+
+// setColorMapperFactory sets the library color mapper built by factory. ANSIColorMapper is built for the current TerminalBackground, and rebuilt by WithTerminalBackground()
+func (a *AsciiConverter) setColorMapperFactory(factory func(bg TerminalBackground) func(LuminosityProvider, int, int) (int, string)) {
+	a.colorMapperFactory = factory
+	a.colorMapperBackground = a.TerminalBackground
+	a.ANSIColorMapper = factory(a.TerminalBackground)
+}
@@ -0,0 +1,63 @@
+package asciiart
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelBandsPerWorker is how many bands of rows each worker gets on average. Smaller bands balance the load better when some rows are more expensive than others
+const parallelBandsPerWorker = 4
+
+// workers returns the number of goroutines each stage of the pipeline is split over. See WithParallelism()
+func (a *AsciiConverter) workers() int {
+	if a.Parallelism <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return a.Parallelism
+}
+
+/*
+parallelRows calls row(y) for every y in [0, height), split into bands of rows over up to workers() goroutines. Every row must only write to its own outputs, so the result is identical to calling row for every y in order.
+
+Cancellation of ctx is checked before every row. ctx.Err() is returned once every goroutine has stopped.
+*/
+func (a *AsciiConverter) parallelRows(ctx context.Context, height int, row func(y int)) error {
+	workers := min(a.workers(), height)
+	if workers <= 1 {
+		for y := range height {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			row(y)
+		}
+		return nil
+	}
+
+	bandHeight := max(1, height / (workers * parallelBandsPerWorker))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Go(func() {
+			for {
+				// Claim the next band
+				y0 := int(next.Add(int64(bandHeight))) - bandHeight
+				if y0 >= height {
+					return
+				}
+
+				for y := y0; y < min(y0 + bandHeight, height); y++ {
+					if ctx.Err() != nil {
+						return
+					}
+					row(y)
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+	return ctx.Err()
+}
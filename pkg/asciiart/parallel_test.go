@@ -0,0 +1,85 @@
+package asciiart
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// sampleImages are the images the CLI is documented with, in ../../asciiart_cmd_images
+var sampleImages = []string{"1-shyguy.png", "2-shyguy.png", "3-mona_lisa.jpg"}
+
+func TestDefaultParallelismIsSerial(t *testing.T) {
+	if got := New().workers(); got != 1 {
+		t.Errorf("New() converts on %d goroutines, want 1", got)
+	}
+	if got := New(WithParallelism(0)).workers(); got != runtime.GOMAXPROCS(0) {
+		t.Errorf("WithParallelism(0) converts on %d goroutines, want runtime.GOMAXPROCS(0) (%d)", got, runtime.GOMAXPROCS(0))
+	}
+}
+
+func TestParallelOutputIsIdentical(t *testing.T) {
+	configs := map[string][]AsciiOption{
+		"default": nil,
+		"no sobel": {WithSobel(false)},
+		"8 bit colour": {WithDefault8BitColorMapper(), WithBoldedSobelOutline(true)},
+		"24 bit colour": {WithDefault24BitColorMapper()},
+		"linear light": {WithLinearLight(true), WithDefault4BitColorMapper()},
+		"filters": {WithPreFilters(GaussianBlurFilter(1)), WithLuminosityFilters(HistogramEqualizationFilter())},
+		"edge supersampling": {WithEdgeSupersampling(2)},
+	}
+
+	for _, file := range sampleImages {
+		img := loadSampleImage(t, file)
+
+		for name, opts := range configs {
+			t.Run(file + "/" + name, func(t *testing.T) {
+				want := New(append(opts, WithParallelism(1))...).ConvertToGrid(img, 120, 60)
+
+				// 7 does not divide the number of rows, so bands have different heights
+				for _, n := range []int{2, 7, 64, 0} {
+					got := New(append(opts, WithParallelism(n))...).ConvertToGrid(img, 120, 60)
+					if !equalCanvasCells(got, want) {
+						t.Errorf("WithParallelism(%d) output differs from WithParallelism(1)", n)
+					}
+				}
+			})
+		}
+	}
+}
+
+// equalCanvasCells reports whether a and b are the same size and have the same cells
+func equalCanvasCells(a, b *Canvas) bool {
+	if a.Width != b.Width || a.Height != b.Height {
+		return false
+	}
+	for y := range a.Cells {
+		for x := range a.Cells[y] {
+			if a.Cells[y][x] != b.Cells[y][x] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func BenchmarkConvert(b *testing.B) {
+	for _, file := range sampleImages {
+		img := loadSampleImage(b, file)
+
+		// 0 uses every CPU
+		for _, n := range []int{1, 2, 4, 8, 0} {
+			name := "workers=" + strconv.Itoa(n)
+			if n == 0 {
+				name = "workers=gomaxprocs"
+			}
+
+			b.Run(file + "/" + name, func(b *testing.B) {
+				a := New(WithDefault8BitColorMapper(), WithParallelism(n))
+				for b.Loop() {
+					a.Convert(img, 200, 100)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+)
+
+/*
+pixelReader returns a function that reads the colour of img at x, y as 16 bit alpha premultiplied r, g, b, a, exactly like img.At(x, y).RGBA().
+
+*image.RGBA, *image.NRGBA, *image.YCbCr, *image.Gray and *image.Paletted are read straight from their pixel slices, so no color.Color is allocated for every pixel. Points outside the bounds of img, and any other image type, go through img.At().
+*/
+func pixelReader(img image.Image) func(x, y int) (r, g, b, a uint32) {
+	switch img := img.(type) {
+		case *image.RGBA:
+			return func(x, y int) (uint32, uint32, uint32, uint32) {
+				if !(image.Point{x, y}.In(img.Rect)) {
+					return img.At(x, y).RGBA()
+				}
+
+				i := img.PixOffset(x, y)
+				s := img.Pix[i : i + 4 : i + 4]
+				return color.RGBA{s[0], s[1], s[2], s[3]}.RGBA()
+			}
+		case *image.NRGBA:
+			return func(x, y int) (uint32, uint32, uint32, uint32) {
+				if !(image.Point{x, y}.In(img.Rect)) {
+					return img.At(x, y).RGBA()
+				}
+
+				i := img.PixOffset(x, y)
+				s := img.Pix[i : i + 4 : i + 4]
+				return color.NRGBA{s[0], s[1], s[2], s[3]}.RGBA()
+			}
+		case *image.YCbCr:
+			// JPEGs decode to YCbCr, so this is the most common input
+			return func(x, y int) (uint32, uint32, uint32, uint32) {
+				if !(image.Point{x, y}.In(img.Rect)) {
+					return img.At(x, y).RGBA()
+				}
+
+				yi, ci := img.YOffset(x, y), img.COffset(x, y)
+				return color.YCbCr{img.Y[yi], img.Cb[ci], img.Cr[ci]}.RGBA()
+			}
+		case *image.Gray:
+			return func(x, y int) (uint32, uint32, uint32, uint32) {
+				if !(image.Point{x, y}.In(img.Rect)) {
+					return img.At(x, y).RGBA()
+				}
+
+				return color.Gray{img.Pix[img.PixOffset(x, y)]}.RGBA()
+			}
+		case *image.Paletted:
+			if len(img.Palette) == 0 {
+				break
+			}
+
+			// Convert the palette once instead of every pixel
+			palette := make([][4]uint32, len(img.Palette))
+			for i, c := range img.Palette {
+				r, g, b, a := c.RGBA()
+				palette[i] = [4]uint32{r, g, b, a}
+			}
+
+			return func(x, y int) (uint32, uint32, uint32, uint32) {
+				if !(image.Point{x, y}.In(img.Rect)) {
+					return img.At(x, y).RGBA()
+				}
+
+				c := palette[img.Pix[img.PixOffset(x, y)]]
+				return c[0], c[1], c[2], c[3]
+			}
+	}
+
+	return func(x, y int) (uint32, uint32, uint32, uint32) {
+		return img.At(x, y).RGBA()
+	}
+}
+
+/*
+nrgbaReader returns a function that reads the colour of img at x, y as non alpha premultiplied 8 bit colour, exactly like color.NRGBAModel.Convert(img.At(x, y)). The same image types as pixelReader() are read straight from their pixel slices.
+*/
+func nrgbaReader(img image.Image) func(x, y int) color.NRGBA {
+	switch img := img.(type) {
+		case *image.NRGBA:
+			return func(x, y int) color.NRGBA {
+				if !(image.Point{x, y}.In(img.Rect)) {
+					return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				}
+
+				i := img.PixOffset(x, y)
+				s := img.Pix[i : i + 4 : i + 4]
+				return color.NRGBA{s[0], s[1], s[2], s[3]}
+			}
+		case *image.Paletted:
+			if len(img.Palette) == 0 {
+				break
+			}
+
+			// Convert the palette once instead of every pixel. The palette may already hold color.NRGBA, which NRGBAModel keeps as is
+			palette := make([]color.NRGBA, len(img.Palette))
+			for i, c := range img.Palette {
+				palette[i] = color.NRGBAModel.Convert(c).(color.NRGBA)
+			}
+
+			return func(x, y int) color.NRGBA {
+				if !(image.Point{x, y}.In(img.Rect)) {
+					return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				}
+
+				return palette[img.Pix[img.PixOffset(x, y)]]
+			}
+		case *image.RGBA, *image.YCbCr, *image.Gray:
+			// The colours of these images are never color.NRGBA, so NRGBAModel always converts them from RGBA()
+			read := pixelReader(img)
+			return func(x, y int) color.NRGBA {
+				return nrgbaFromRGBA(read(x, y))
+			}
+	}
+
+	return func(x, y int) color.NRGBA {
+		return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+	}
+}
+
+// nrgbaFromRGBA converts 16 bit alpha premultiplied r, g, b, a to non alpha premultiplied 8 bit colour, with the same rounding as color.NRGBAModel
+func nrgbaFromRGBA(r, g, b, a uint32) color.NRGBA {
+	switch a {
+		case 0xffff:
+			return color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xff}
+		case 0:
+			return color.NRGBA{}
+	}
+
+	r = (r * 0xffff) / a
+	g = (g * 0xffff) / a
+	b = (b * 0xffff) / a
+	return color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+}
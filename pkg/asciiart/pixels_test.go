@@ -0,0 +1,196 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"testing"
+)
+
+// pixelsRect has a non-zero origin, including negative coordinates, and odd sizes so subsampled chroma does not line up with the edges
+var pixelsRect = image.Rect(-3, 5, 8, 14)
+
+// pixelTestImages returns an image of every type the pixel readers read directly, and one they do not, filled with random colours
+func pixelTestImages() map[string]image.Image {
+	rng := rand.New(rand.NewPCG(1, 2))
+	fill := func(pix []uint8) {
+		for i := range pix {
+			pix[i] = uint8(rng.UintN(256))
+		}
+	}
+
+	images := map[string]image.Image{}
+
+	rgba := image.NewRGBA(pixelsRect)
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		// Keep the colour alpha premultiplied, so no channel exceeds alpha
+		a := uint8(rng.UintN(256))
+		for c := range 3 {
+			rgba.Pix[i + c] = uint8(rng.UintN(uint(a) + 1))
+		}
+		rgba.Pix[i + 3] = a
+	}
+	images["rgba"] = rgba
+
+	nrgba := image.NewNRGBA(pixelsRect)
+	fill(nrgba.Pix)
+	// Fully transparent and opaque pixels take their own paths through NRGBAModel
+	nrgba.Pix[3], nrgba.Pix[7] = 0, 255
+	images["nrgba"] = nrgba
+
+	ratios := map[string]image.YCbCrSubsampleRatio{
+		"444": image.YCbCrSubsampleRatio444,
+		"422": image.YCbCrSubsampleRatio422,
+		"420": image.YCbCrSubsampleRatio420,
+		"440": image.YCbCrSubsampleRatio440,
+		"411": image.YCbCrSubsampleRatio411,
+		"410": image.YCbCrSubsampleRatio410,
+	}
+	for name, ratio := range ratios {
+		ycbcr := image.NewYCbCr(pixelsRect, ratio)
+		fill(ycbcr.Y)
+		fill(ycbcr.Cb)
+		fill(ycbcr.Cr)
+		images["ycbcr " + name] = ycbcr
+	}
+
+	gray := image.NewGray(pixelsRect)
+	fill(gray.Pix)
+	images["gray"] = gray
+
+	palette := color.Palette{
+		color.Black,
+		color.White,
+		color.RGBA{200, 100, 50, 255},
+		color.NRGBA{200, 100, 50, 128},
+		color.RGBA{},
+		color.Gray16{0x1234},
+	}
+	paletted := image.NewPaletted(pixelsRect, palette)
+	for i := range paletted.Pix {
+		paletted.Pix[i] = uint8(rng.UintN(uint(len(palette))))
+	}
+	images["paletted"] = paletted
+
+	// Any other type goes through img.At()
+	gray16 := image.NewGray16(pixelsRect)
+	fill(gray16.Pix)
+	images["gray16"] = gray16
+
+	// Sub images share the pixels of their parent, with a different origin and stride
+	images["rgba sub image"] = rgba.SubImage(image.Rect(0, 7, 5, 12))
+	images["ycbcr 420 sub image"] = images["ycbcr 420"].(*image.YCbCr).SubImage(image.Rect(-1, 6, 6, 13))
+	images["paletted sub image"] = paletted.SubImage(image.Rect(1, 8, 8, 14))
+
+	return images
+}
+
+func TestPixelReadersMatchAt(t *testing.T) {
+	for name, img := range pixelTestImages() {
+		t.Run(name, func(t *testing.T) {
+			read := pixelReader(img)
+			readNRGBA := nrgbaReader(img)
+
+			// Include a border outside the bounds, which must be read through img.At() too
+			bounds := img.Bounds().Inset(-1)
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					r, g, b, a := read(x, y)
+					wr, wg, wb, wa := img.At(x, y).RGBA()
+					if r != wr || g != wg || b != wb || a != wa {
+						t.Fatalf("pixelReader at (%d, %d) = %d, %d, %d, %d, want %d, %d, %d, %d", x, y, r, g, b, a, wr, wg, wb, wa)
+					}
+
+					if got, want := readNRGBA(x, y), color.NRGBAModel.Convert(img.At(x, y)); got != want {
+						t.Fatalf("nrgbaReader at (%d, %d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPalettedIndexOutOfRange(t *testing.T) {
+	img := image.NewPaletted(pixelsRect, color.Palette{color.Black, color.White})
+	img.SetColorIndex(0, 6, 2)
+
+	// img.At() panics on an index outside the palette, and so must the readers, rather than reading another colour
+	mustPanic := func(name string, read func()) {
+		t.Helper()
+
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s did not panic on an index outside the palette", name)
+			}
+		}()
+		read()
+	}
+
+	mustPanic("img.At()", func() { img.At(0, 6) })
+	mustPanic("pixelReader", func() { pixelReader(img)(0, 6) })
+	mustPanic("nrgbaReader", func() { nrgbaReader(img)(0, 6) })
+
+	// Every other pixel is still read
+	if r, _, _, _ := pixelReader(img)(1, 6); r != 0 {
+		t.Errorf("pixelReader at (1, 6) red = %d, want 0", r)
+	}
+}
+
+// pixelSink sums the alpha of every pixel read by the benchmarks, so the reads cannot be optimised away
+var pixelSink uint32
+
+func BenchmarkPixelReader(b *testing.B) {
+	// JPEGs decode to *image.YCbCr, the slowest type to read through img.At()
+	img := loadSampleImage(b, "3-mona_lisa.jpg")
+	if _, ok := img.(*image.YCbCr); !ok {
+		b.Fatalf("sample decoded to %T, want *image.YCbCr", img)
+	}
+	bounds := img.Bounds()
+
+	readers := []struct {
+		name	string
+		read	func(x, y int) uint32
+	}{
+		{"At", func(x, y int) uint32 {
+			_, _, _, a := img.At(x, y).RGBA()
+			return a
+		}},
+		{"pixelReader", func() func(x, y int) uint32 {
+			read := pixelReader(img)
+			return func(x, y int) uint32 {
+				_, _, _, a := read(x, y)
+				return a
+			}
+		}()},
+		{"NRGBAModel", func(x, y int) uint32 {
+			return uint32(color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA).A)
+		}},
+		{"nrgbaReader", func() func(x, y int) uint32 {
+			read := nrgbaReader(img)
+			return func(x, y int) uint32 {
+				return uint32(read(x, y).A)
+			}
+		}()},
+	}
+
+	for _, r := range readers {
+		b.Run(r.name, func(b *testing.B) {
+			for b.Loop() {
+				for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+					for x := bounds.Min.X; x < bounds.Max.X; x++ {
+						pixelSink += r.read(x, y)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMapLuminosityJPEG(b *testing.B) {
+	img := loadSampleImage(b, "3-mona_lisa.jpg")
+	a := New()
+
+	for b.Loop() {
+		a.MapLuminosity(img)
+	}
+}
@@ -0,0 +1,186 @@
+package asciiart
+
+import (
+	"math"
+	"slices"
+)
+
+/*
+LuminosityFilter reads the luminosity grid of src and writes the filtered luminosity (0-255) into dst. dst and src always have the same dimensions and never share the same backing data, so a filter can freely read its neighbourhood from src while writing to dst.
+*/
+type LuminosityFilter func(dst, src LuminosityProvider)
+
+/*
+ApplyPreFilters runs the PreFilters chain over lumImg and returns the filtered luminosity. lumImg itself is never modified. If no pre-filters are configured, lumImg is returned as is.
+*/
+func (a *AsciiConverter) ApplyPreFilters(lumImg LuminosityProvider) LuminosityProvider {
+	if len(a.PreFilters) == 0 {
+		return lumImg
+	}
+
+	// Ping-pong between two buffers so a chain of any length only allocates twice
+	buffers := [2]defaultLuminosityProvider{
+		makeDefaultLuminosityImage(lumImg),
+		makeDefaultLuminosityImage(lumImg),
+	}
+
+	src := lumImg
+	for i, filter := range a.PreFilters {
+		dst := buffers[i%2]
+		filter(dst, src)
+		src = dst
+	}
+
+	return src
+}
+
+/*
+GaussianBlurFilter returns a LuminosityFilter that blurs the luminosity grid with a gaussian kernel of standard deviation sigma (measured in characters). The kernel is separable, so it is applied horizontally then vertically. A sigma <= 0 leaves the luminosity untouched.
+
+A sigma between 0.5 and 1.5 is usually enough to stop JPEG noise and fine texture from registering as edges.
+*/
+func GaussianBlurFilter(sigma float64) LuminosityFilter {
+	if sigma <= 0 {
+		return copyFilter
+	}
+
+	// 3 sigma covers >99% of the gaussian, anything further out is negligible
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*radius+1)
+
+	var sum float64
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-(d * d) / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	return func(dst, src LuminosityProvider) {
+		width, height := src.Width(), src.Height()
+		horizontal := make([]float64, width*height)
+
+		for y := range height {
+			for x := range width {
+				var acc float64
+				for k := -radius; k <= radius; k++ {
+					acc += kernel[k+radius] * float64(src.SafeLuminosityAt(x+k, y))
+				}
+				horizontal[x+y*width] = acc
+			}
+		}
+
+		for y := range height {
+			for x := range width {
+				var acc float64
+				for k := -radius; k <= radius; k++ {
+					yk := min(height-1, max(0, y+k))
+					acc += kernel[k+radius] * horizontal[x+yk*width]
+				}
+				dst.LuminositySet(x, y, clampLuminosity(acc))
+			}
+		}
+	}
+}
+
+/*
+MedianFilter returns a LuminosityFilter that replaces each character's luminosity with the median of the (2*radius+1)x(2*radius+1) window around it. The median filter removes salt and pepper noise while keeping hard edges intact. A radius <= 0 leaves the luminosity untouched.
+*/
+func MedianFilter(radius int) LuminosityFilter {
+	if radius <= 0 {
+		return copyFilter
+	}
+
+	return func(dst, src LuminosityProvider) {
+		width, height := src.Width(), src.Height()
+		window := make([]int, 0, (2*radius+1)*(2*radius+1))
+
+		for y := range height {
+			for x := range width {
+				window = window[:0]
+				for dy := -radius; dy <= radius; dy++ {
+					for dx := -radius; dx <= radius; dx++ {
+						window = append(window, src.SafeLuminosityAt(x+dx, y+dy))
+					}
+				}
+
+				slices.Sort(window)
+				dst.LuminositySet(x, y, window[len(window)/2])
+			}
+		}
+	}
+}
+
+/*
+BilateralFilter returns an edge preserving LuminosityFilter. Like GaussianBlurFilter, each character becomes a weighted average of its neighbours within radius, but neighbours are additionally weighted by how similar their luminosity is. This smooths out texture while leaving strong luminosity steps (real edges) sharp.
+	- sigmaSpatial is the standard deviation of the spatial weight (in characters)
+	- sigmaRange is the standard deviation of the luminosity weight (0-255). Differences much larger than sigmaRange are treated as edges and are not averaged across.
+
+A radius <= 0, sigmaSpatial <= 0 or sigmaRange <= 0 leaves the luminosity untouched.
+*/
+func BilateralFilter(radius int, sigmaSpatial, sigmaRange float64) LuminosityFilter {
+	if radius <= 0 || sigmaSpatial <= 0 || sigmaRange <= 0 {
+		return copyFilter
+	}
+
+	side := 2*radius + 1
+	spatialWeights := make([]float64, side*side)
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			d2 := float64(dx*dx + dy*dy)
+			spatialWeights[(dx+radius)+(dy+radius)*side] = math.Exp(-d2 / (2 * sigmaSpatial * sigmaSpatial))
+		}
+	}
+
+	// Luminosity differences are always in [0, 255], so the range weights can be precomputed
+	var rangeWeights [256]float64
+	for d := range rangeWeights {
+		fd := float64(d)
+		rangeWeights[d] = math.Exp(-(fd * fd) / (2 * sigmaRange * sigmaRange))
+	}
+
+	return func(dst, src LuminosityProvider) {
+		width, height := src.Width(), src.Height()
+
+		for y := range height {
+			for x := range width {
+				centre := src.LuminosityAt(x, y)
+
+				var acc, weightSum float64
+				for dy := -radius; dy <= radius; dy++ {
+					for dx := -radius; dx <= radius; dx++ {
+						lum := src.SafeLuminosityAt(x+dx, y+dy)
+						diff := lum - centre
+						if diff < 0 {
+							diff = -diff
+						}
+
+						w := spatialWeights[(dx+radius)+(dy+radius)*side] * rangeWeights[min(255, diff)]
+						acc += w * float64(lum)
+						weightSum += w
+					}
+				}
+
+				dst.LuminositySet(x, y, clampLuminosity(acc/weightSum))
+			}
+		}
+	}
+}
+
+// copyFilter is the identity LuminosityFilter, used when a filter is configured with parameters that would have no effect
+func copyFilter(dst, src LuminosityProvider) {
+	width, height := src.Width(), src.Height()
+	for y := range height {
+		for x := range width {
+			dst.LuminositySet(x, y, src.LuminosityAt(x, y))
+		}
+	}
+}
+
+// clampLuminosity rounds a filtered luminosity value and clamps it to [0, 255]
+func clampLuminosity(lum float64) int {
+	return min(255, max(0, int(math.Round(lum))))
+}
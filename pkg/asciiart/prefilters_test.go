@@ -0,0 +1,142 @@
+package asciiart
+
+import (
+	"slices"
+	"testing"
+)
+
+// luminosityRows reads the luminosity grid of lumImg back into rows
+func luminosityRows(lumImg LuminosityProvider) [][]int {
+	rows := make([][]int, lumImg.Height())
+	for y := range rows {
+		rows[y] = make([]int, lumImg.Width())
+		for x := range rows[y] {
+			rows[y][x] = lumImg.LuminosityAt(x, y)
+		}
+	}
+	return rows
+}
+
+// applyFilter runs filter once over rows and returns the filtered rows
+func applyFilter(filter LuminosityFilter, rows [][]int) [][]int {
+	src := makeTestLuminosity(rows)
+	dst := makeDefaultLuminosityImage(src)
+	filter(dst, src)
+	return luminosityRows(dst)
+}
+
+func equalRows(a, b [][]int) bool {
+	return slices.EqualFunc(a, b, slices.Equal)
+}
+
+func TestPreFiltersKeepFlatAreas(t *testing.T) {
+	flat := verticalStep(7, 7, 7, 90, 90)
+
+	filters := map[string]LuminosityFilter{
+		"gaussian": GaussianBlurFilter(1),
+		"median": MedianFilter(2),
+		"bilateral": BilateralFilter(2, 1, 20),
+	}
+
+	for name, filter := range filters {
+		t.Run(name, func(t *testing.T) {
+			if got := applyFilter(filter, flat); !equalRows(got, flat) {
+				t.Errorf("flat grid changed to %v", got)
+			}
+		})
+	}
+}
+
+func TestPreFiltersDisabledCopy(t *testing.T) {
+	step := verticalStep(7, 3, 3, 0, 200)
+
+	filters := map[string]LuminosityFilter{
+		"gaussian sigma 0": GaussianBlurFilter(0),
+		"median radius 0": MedianFilter(0),
+		"bilateral radius 0": BilateralFilter(0, 1, 20),
+		"bilateral sigma range 0": BilateralFilter(2, 1, 0),
+	}
+
+	for name, filter := range filters {
+		t.Run(name, func(t *testing.T) {
+			if got := applyFilter(filter, step); !equalRows(got, step) {
+				t.Errorf("got %v, want the input unchanged", got)
+			}
+		})
+	}
+}
+
+func TestGaussianBlurFilterSmoothsStep(t *testing.T) {
+	got := applyFilter(GaussianBlurFilter(1), verticalStep(9, 3, 5, 0, 200))
+
+	row := got[1]
+	if row[0] != 0 || row[8] != 200 {
+		t.Errorf("far sides of the step = %d, %d, want 0, 200", row[0], row[8])
+	}
+	for x := 1; x < len(row); x++ {
+		if row[x] < row[x-1] {
+			t.Fatalf("blurred step is not increasing: %v", row)
+		}
+	}
+	if row[4] <= 0 || row[5] >= 200 {
+		t.Errorf("step was not blurred: %v", row)
+	}
+}
+
+func TestMedianFilterRemovesSaltKeepsStep(t *testing.T) {
+	rows := verticalStep(9, 5, 5, 0, 200)
+	rows[2][1] = 255
+
+	got := applyFilter(MedianFilter(1), rows)
+
+	want := verticalStep(9, 5, 5, 0, 200)
+	if !equalRows(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBilateralFilterKeepsHardStep(t *testing.T) {
+	rows := verticalStep(9, 5, 5, 0, 200)
+	// Low contrast texture either side of the step
+	rows[2][1] = 10
+	rows[2][7] = 190
+
+	got := applyFilter(BilateralFilter(2, 1.5, 20), rows)
+
+	if got[2][4] > 10 || got[2][5] < 190 {
+		t.Errorf("step either side = %d, %d, want it to stay sharp", got[2][4], got[2][5])
+	}
+	if got[2][1] >= 10 || got[2][7] <= 190 {
+		t.Errorf("texture = %d, %d, want it smoothed towards its neighbours", got[2][1], got[2][7])
+	}
+}
+
+func TestApplyPreFilters(t *testing.T) {
+	rows := verticalStep(9, 5, 5, 0, 200)
+	rows[2][1] = 255
+
+	t.Run("no filters returns the input", func(t *testing.T) {
+		lumImg := makeTestLuminosity(rows)
+		a := New()
+
+		got := a.ApplyPreFilters(lumImg)
+		if !equalRows(luminosityRows(got), rows) {
+			t.Errorf("got %v, want the input unchanged", luminosityRows(got))
+		}
+	})
+
+	t.Run("chain runs in order and leaves the input alone", func(t *testing.T) {
+		lumImg := makeTestLuminosity(rows)
+		a := New(WithPreFilters(MedianFilter(1), GaussianBlurFilter(1), MedianFilter(1)))
+
+		got := luminosityRows(a.ApplyPreFilters(lumImg))
+
+		want := applyFilter(MedianFilter(1), applyFilter(GaussianBlurFilter(1), applyFilter(MedianFilter(1), rows)))
+		if !equalRows(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if !equalRows(luminosityRows(lumImg), rows) {
+			t.Errorf("input was modified to %v", luminosityRows(lumImg))
+		}
+	})
+}
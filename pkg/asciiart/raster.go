@@ -0,0 +1,113 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+)
+
+const (
+	defaultImageCellWidth	= 8
+	defaultImageCellHeight	= 16
+)
+
+/*
+ImageRendererOptions represents the configuration of RenderImage() and the PNG/GIF renderers. The zero value draws 8x16 cells in white on black.
+*/
+type ImageRendererOptions struct {
+	// CellWidth is the width of each character in pixels. Defaults to 8
+	CellWidth		int
+	// CellHeight is the height of each character in pixels. Defaults to 16 (the 8x8 font stretched to the 1:2 shape of a terminal character)
+	CellHeight		int
+	// Foreground is the colour of characters without colour (see Cell.HasColor()). Defaults to white
+	Foreground		color.Color
+	// Background fills the whole image. Defaults to black. Use color.Transparent for a transparent image
+	Background		color.Color
+}
+
+/*
+RenderImage draws canvas to an image using the embedded 8x8 bitmap font (no system fonts are needed). Each glyph is scaled with nearest neighbour sampling to CellWidth x CellHeight pixels. Characters are drawn in the colour they are displayed with in a terminal (the palette colour of their escape sequence, like HTMLRenderer() and SVGRenderer()), cell backgrounds (see Cell.BG) are honoured, and bold characters are emboldened by drawing them twice, 1 pixel apart.
+*/
+func RenderImage(canvas *Canvas, opts ImageRendererOptions) *image.RGBA {
+	cw, ch := opts.CellWidth, opts.CellHeight
+	if cw <= 0 {
+		cw = defaultImageCellWidth
+	}
+	if ch <= 0 {
+		ch = defaultImageCellHeight
+	}
+
+	var fg, bg color.Color = color.White, color.Black
+	if opts.Foreground != nil {
+		fg = opts.Foreground
+	}
+	if opts.Background != nil {
+		bg = opts.Background
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, canvas.Width * cw, canvas.Height * ch))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	defaultFG := color.RGBAModel.Convert(fg).(color.RGBA)
+
+	for y, row := range canvas.Cells {
+		for x, cell := range row {
+			if cell.Transparent {
+				continue
+			}
+
+			cellRect := image.Rect(x * cw, y * ch, (x + 1) * cw, (y + 1) * ch)
+			if cell.BG.A != 0 {
+				draw.Draw(img, cellRect, image.NewUniform(cell.BG), image.Point{}, draw.Over)
+			}
+
+			cellFG := defaultFG
+			if cell.HasColor() {
+				cellFG = cellDisplayColor(cell)
+			}
+
+			drawGlyph(img, cellRect, glyphFor(cell.Rune), cellFG, cell.Bold)
+		}
+	}
+
+	return img
+}
+
+// drawGlyph draws glyph scaled to fill rect. A bold glyph is drawn again 1 pixel to the right, clipped to rect
+func drawGlyph(img *image.RGBA, rect image.Rectangle, glyph *[fontGlyphSize]byte, c color.RGBA, bold bool) {
+	cw, ch := rect.Dx(), rect.Dy()
+
+	for py := range ch {
+		bits := glyph[py * fontGlyphSize / ch]
+		if bold {
+			bits |= bits << 1
+		}
+
+		for px := range cw {
+			if bits & (1 << (px * fontGlyphSize / cw)) != 0 {
+				img.SetRGBA(rect.Min.X + px, rect.Min.Y + py, c)
+			}
+		}
+	}
+}
+
+/*
+PNGRenderer returns a Renderer that draws the canvas with RenderImage() and encodes it as a PNG.
+*/
+func PNGRenderer(opts ImageRendererOptions) Renderer {
+	return func(w io.Writer, canvas *Canvas) error {
+		return png.Encode(w, RenderImage(canvas, opts))
+	}
+}
+
+/*
+GIFRenderer returns a Renderer that draws the canvas with RenderImage() and encodes it as a GIF. Colours are reduced to the 256 colour Plan 9 palette with dithering.
+*/
+func GIFRenderer(opts ImageRendererOptions) Renderer {
+	return func(w io.Writer, canvas *Canvas) error {
+		return gif.Encode(w, RenderImage(canvas, opts), nil)
+	}
+}
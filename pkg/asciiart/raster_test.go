@@ -0,0 +1,163 @@
+package asciiart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+// rasterCanvas is a red '#', a bold '-' on blue and a transparent cell on blue
+func rasterCanvas() *Canvas {
+	// The true colour of the '#' differs from the red of its escape sequence, which is what is drawn
+	red := color.RGBA{250, 10, 10, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	return testCanvas([]Cell{
+		{Rune: '#', FG: red, ColorEscape: "\x1b[91m"},
+		{Rune: '-', Bold: true, BG: blue},
+		{Rune: ' ', Transparent: true, BG: blue},
+	})
+}
+
+func TestRenderImage(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	white := color.RGBA{255, 255, 255, 255}
+	red := ansi16Colors[9]
+	blue := color.RGBA{0, 0, 255, 255}
+
+	img := RenderImage(rasterCanvas(), ImageRendererOptions{})
+	if got := img.Bounds(); got != image.Rect(0, 0, 24, 16) {
+		t.Fatalf("bounds = %v, want 24x16", got)
+	}
+
+	tests := []struct {
+		name	string
+		x, y	int
+		want	color.RGBA
+	}{
+		// The top row of '#' is 0x36, and every font row is stretched over 2 pixels
+		{"glyph pixel in the palette colour of the cell", 1, 1, red},
+		{"unset glyph pixel", 0, 0, black},
+		{"last glyph row is blank", 1, 15, black},
+		// '-' is 0x3F on font row 3. Bold adds pixel 6
+		{"glyph pixel in the default colour", 8, 6, white},
+		{"bold pixel", 14, 6, white},
+		{"cell background", 15, 6, blue},
+		{"cell background above the glyph", 8, 0, blue},
+		{"transparent cells are not drawn", 20, 8, black},
+	}
+
+	for _, tt := range tests {
+		if got := img.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("%s: pixel (%d, %d) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestRenderImageUsesPaletteColours(t *testing.T) {
+	canvas := New(WithDefault4BitColorMapper(), WithOutputAspectRatio(1)).ConvertToGrid(loadSampleImage(t, "3-mona_lisa.jpg"), 40, 40)
+
+	// Every pixel is the black background, the white default foreground or one of the 16 colours of the 4 bit mapper
+	allowed := map[color.RGBA]bool{{0, 0, 0, 255}: true, {255, 255, 255, 255}: true}
+	for _, c := range ansi16Colors {
+		allowed[c] = true
+	}
+
+	img := RenderImage(canvas, ImageRendererOptions{})
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if c := img.RGBAAt(x, y); !allowed[c] {
+				t.Fatalf("pixel (%d, %d) = %v, want a colour of the 4 bit palette", x, y, c)
+			}
+		}
+	}
+}
+
+func TestRenderImageOptions(t *testing.T) {
+	fg := color.RGBA{10, 20, 30, 255}
+	bg := color.RGBA{200, 200, 200, 255}
+
+	// A 4x4 cell samples every other font row and column
+	img := RenderImage(testCanvas([]Cell{{Rune: '#'}}), ImageRendererOptions{CellWidth: 4, CellHeight: 4, Foreground: fg, Background: bg})
+	if got := img.Bounds(); got != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("bounds = %v, want 4x4", got)
+	}
+
+	// Font row 0 is 0x36: columns 0, 2, 4 and 6 are 0, 1, 1 and 0
+	for x, want := range []color.RGBA{bg, fg, fg, bg} {
+		if got := img.RGBAAt(x, 0); got != want {
+			t.Errorf("pixel (%d, 0) = %v, want %v", x, got, want)
+		}
+	}
+
+	img = RenderImage(testCanvas([]Cell{{Rune: ' '}}), ImageRendererOptions{Background: color.Transparent})
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{}) {
+		t.Errorf("transparent background pixel = %v", got)
+	}
+}
+
+func TestUnknownRunesUseAFallbackGlyph(t *testing.T) {
+	img := RenderImage(testCanvas([]Cell{{Rune: 'é'}, {Rune: ' '}}), ImageRendererOptions{})
+
+	lit := 0
+	for y := range 16 {
+		for x := range 8 {
+			if img.RGBAAt(x, y) != (color.RGBA{0, 0, 0, 255}) {
+				lit++
+			}
+		}
+	}
+	if lit == 0 {
+		t.Error("rune outside the font was not drawn")
+	}
+}
+
+func TestPNGRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PNGRenderer(ImageRendererOptions{})(&buf, rasterCanvas()); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := RenderImage(rasterCanvas(), ImageRendererOptions{})
+	if decoded.Bounds() != want.Bounds() {
+		t.Fatalf("bounds = %v, want %v", decoded.Bounds(), want.Bounds())
+	}
+	for y := range want.Bounds().Dy() {
+		for x := range want.Bounds().Dx() {
+			if got := color.RGBAModel.Convert(decoded.At(x, y)); got != want.RGBAAt(x, y) {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, want.RGBAAt(x, y))
+			}
+		}
+	}
+}
+
+func TestGIFRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	if err := GIFRenderer(ImageRendererOptions{})(&buf, rasterCanvas()); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := gif.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Bounds(); got != image.Rect(0, 0, 24, 16) {
+		t.Fatalf("bounds = %v, want 24x16", got)
+	}
+
+	// Black, white, red and blue are all in the Plan 9 palette
+	for _, p := range []image.Point{{0, 0}, {1, 1}, {8, 6}, {15, 6}} {
+		want := RenderImage(rasterCanvas(), ImageRendererOptions{}).RGBAAt(p.X, p.Y)
+		if got := color.RGBAModel.Convert(decoded.At(p.X, p.Y)); got != want {
+			t.Errorf("pixel %v = %v, want %v", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package asciiart
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+/*
+Renderer writes a Canvas to w in some output format. The converter renders with ANSIRenderer() by default. See WithRenderer()
+*/
+type Renderer func(w io.Writer, canvas *Canvas) error
+
+/*
+ANSIRendererOptions represents the configuration of the ANSI renderer.
+*/
+type ANSIRendererOptions struct {
+	// ResetAtLineEnd resets all styles at the end of every line, so every line can be copied, or viewed (e.g. with `less -R`), on its own
+	ResetAtLineEnd		bool
+}
+
+/*
+ANSIRenderer returns a Renderer that writes the canvas as text with ANSI escape sequences for colour, background and bold, for display in a terminal. This is the default renderer. Cell backgrounds (see WithCellBackgrounds()) are written as true colour, and transparent cells are written without one.
+
+An escape sequence is only written when the style changes from the previous character, and all changes are combined into a single sequence. All styles are reset at the end of the output.
+
+The options are optional, e.g. ANSIRenderer() or ANSIRenderer(ANSIRendererOptions{ResetAtLineEnd: true}). Only the first is used. A converter without a Renderer writes the same output, and can stream it (see ConvertTo() and WithANSIRendererOptions()).
+*/
+func ANSIRenderer(options ...ANSIRendererOptions) Renderer {
+	var opts ANSIRendererOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	return func(w io.Writer, canvas *Canvas) error {
+		aw := newANSIWriter(w, opts, canvas.EdgesDetected)
+		for _, row := range canvas.Cells {
+			aw.writeRow(row)
+		}
+		return aw.finish()
+	}
+}
+
+/*
+ansiWriter writes the rows of a canvas as ANSIRenderer() does, one at a time, so ConvertTo() can write each row as soon as it is generated.
+*/
+type ansiWriter struct {
+	w		*bufio.Writer
+	sgr		*sgrWriter
+	opts	ANSIRendererOptions
+}
+
+// newANSIWriter returns an ansiWriter writing to w, for a canvas that was converted with edge detection if edgesDetected is set
+func newANSIWriter(w io.Writer, opts ANSIRendererOptions, edgesDetected bool) *ansiWriter {
+	bw := bufio.NewWriter(w)
+	if edgesDetected {
+		// Reset everything before we write, so bold from previous output does not leak into the outlines
+		bw.WriteString("\x1b[0m")
+	}
+
+	return &ansiWriter {
+		w: bw,
+		sgr: newSGRWriter(bw),
+		opts: opts,
+	}
+}
+
+// writeRow writes a row of cells, followed by a newline
+func (aw *ansiWriter) writeRow(row []Cell) {
+	for _, cell := range row {
+		aw.sgr.set(drawnStyle(cell, aw.sgr.style))
+		if cell.Transparent {
+			aw.w.WriteRune(' ')
+			continue
+		}
+
+		aw.w.WriteRune(cell.Rune)
+	}
+
+	if aw.opts.ResetAtLineEnd {
+		aw.sgr.reset()
+	}
+	aw.w.WriteRune('\n')
+}
+
+// finish resets all styles and flushes the output, returning the first error from writing it
+func (aw *ansiWriter) finish() error {
+	aw.sgr.reset()
+	return aw.w.Flush()
+}
+
+/*
+TextRendererOptions represents the configuration of the plain text renderer.
+*/
+type TextRendererOptions struct {
+	// TrimTrailingSpace removes spaces at the end of every line
+	TrimTrailingSpace	bool
+}
+
+/*
+TextRenderer returns a Renderer that writes only the characters of the canvas and new lines, without any escape sequences. Colour and bold are dropped. The output is suitable for plain text files, code comments and commit messages.
+*/
+func TextRenderer(opts TextRendererOptions) Renderer {
+	return func(w io.Writer, canvas *Canvas) error {
+		bw := bufio.NewWriter(w)
+
+		line := make([]rune, 0, canvas.Width)
+		for _, row := range canvas.Cells {
+			line = line[:0]
+			for _, cell := range row {
+				line = append(line, cell.Rune)
+			}
+
+			if opts.TrimTrailingSpace {
+				for len(line) > 0 && line[len(line) - 1] == ' ' {
+					line = line[:len(line) - 1]
+				}
+			}
+
+			bw.WriteString(string(line))
+			bw.WriteRune('\n')
+		}
+
+		return bw.Flush()
+	}
+}
+
+// outputBufferSize is the number of bytes to reserve for the rendered output of a width x height canvas
+func (a *AsciiConverter) outputBufferSize(width, height int) int {
+	// In most cases, we will overallocate by a few hundred bytes to ensure there is no reallocation of the buffer
+	// This is because it cannot be known how much room should be left for the colour ANSI escape sequences
+	return int((a.BytesPerCharToReserve + a.AdditionalBytesPerCharColor) * float64(width + 1) * float64(height)) // width + 1 because leave a byte for the new line byte
+}
+
+// renderer returns the Renderer of the converter, falling back to ANSIRenderer() with the ANSIRendererOptions of the converter if none is set
+func (a *AsciiConverter) renderer() Renderer {
+	if a.Renderer == nil {
+		return ANSIRenderer(a.ANSIRendererOptions)
+	}
+	return a.Renderer
+}
+
+/*
+renderString renders canvas to a string with renderer. Writing to a strings.Builder cannot fail, so an error can only come from the renderer itself. The output written before the error is returned along with it.
+*/
+func (a *AsciiConverter) renderString(renderer Renderer, canvas *Canvas) (string, error) {
+	var builder strings.Builder
+	builder.Grow(a.outputBufferSize(canvas.Width, canvas.Height))
+
+	err := renderer(&builder, canvas)
+	return builder.String(), err
+}
@@ -0,0 +1,302 @@
+package asciiart
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// testCanvas builds a canvas from rows of cells
+func testCanvas(rows ...[]Cell) *Canvas {
+	return &Canvas {
+		Width: len(rows[0]),
+		Height: len(rows),
+		Cells: rows,
+		AspectRatio: 1,
+	}
+}
+
+// styledCanvas is a 3x2 canvas with colour, bold, an uncoloured and a transparent cell
+func styledCanvas() *Canvas {
+	red, green := "\x1b[31m", "\x1b[32m"
+	return testCanvas(
+		[]Cell{{Rune: 'a', ColorEscape: red}, {Rune: 'b', ColorEscape: red}, {Rune: 'c', ColorEscape: green, Bold: true}},
+		[]Cell{{Rune: 'd'}, {Rune: ' ', Transparent: true}, {Rune: 'e', ColorEscape: green}},
+	)
+}
+
+// gradientImage returns a 4x2 image, a red ramp above a grey ramp
+func gradientImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := range 4 {
+		v := uint8(x * 85)
+		img.Set(x, 0, color.RGBA{v, 0, 0, 255})
+		img.Set(x, 1, color.RGBA{v, v, v, 255})
+	}
+	return img
+}
+
+func TestANSIRenderer(t *testing.T) {
+	tests := []struct {
+		name	string
+		opts	ANSIRendererOptions
+		canvas	*Canvas
+		want	string
+	}{
+		{
+			name: "styles change only when needed",
+			canvas: styledCanvas(),
+			want: "\x1b[31mab\x1b[32;1mc\n\x1b[0md \x1b[32me\n\x1b[0m",
+		},
+		{
+			name: "reset at line end",
+			opts: ANSIRendererOptions{ResetAtLineEnd: true},
+			canvas: styledCanvas(),
+			want: "\x1b[31mab\x1b[32;1mc\x1b[0m\nd \x1b[32me\x1b[0m\n",
+		},
+		{
+			name: "edges reset first",
+			canvas: &Canvas{Width: 1, Height: 1, Cells: [][]Cell{{{Rune: '|', Bold: true}}}, EdgesDetected: true},
+			want: "\x1b[0m\x1b[1m|\n\x1b[0m",
+		},
+		{
+			name: "cell backgrounds, removed for transparent cells",
+			canvas: testCanvas([]Cell{
+				{Rune: 'a', ColorEscape: "\x1b[31m", BG: color.RGBA{1, 2, 3, 255}},
+				{Rune: ' ', Transparent: true},
+				{Rune: 'b', ColorEscape: "\x1b[31m", BG: color.RGBA{1, 2, 3, 255}},
+			}),
+			want: "\x1b[31;48;2;1;2;3ma\x1b[49m \x1b[48;2;1;2;3mb\n\x1b[0m",
+		},
+		{
+			name: "plain cells have no escapes",
+			canvas: testCanvas([]Cell{{Rune: 'a'}, {Rune: 'b'}}, []Cell{{Rune: 'c'}, {Rune: 'd'}}),
+			want: "ab\ncd\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := ANSIRenderer(tt.opts)(&buf, tt.canvas); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertGolden(t *testing.T) {
+	tests := []struct {
+		name	string
+		opts	[]AsciiOption
+		want	string
+	}{
+		{"no colour", nil, " ^I<\n {Q$\n"},
+		{"4 bit colour", []AsciiOption{WithDefault4BitColorMapper()}, "\x1b[30m ^I\x1b[31m<\n\x1b[30m {\x1b[97mQ$\n\x1b[0m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(append([]AsciiOption{WithSobel(false), WithOutputAspectRatio(1)}, tt.opts...)...)
+
+			canvas := a.ConvertToGrid(gradientImage(), 4, 2)
+			if canvas.Width != 4 || canvas.Height != 2 {
+				t.Fatalf("canvas is %dx%d, want 4x2", canvas.Width, canvas.Height)
+			}
+
+			if got := a.Convert(gradientImage(), 4, 2); got != tt.want {
+				t.Errorf("Convert() = %q, want %q", got, tt.want)
+			}
+
+			var buf bytes.Buffer
+			if err := a.ConvertTo(&buf, gradientImage(), 4, 2); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("ConvertTo() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+var errTestRenderer = errors.New("Renderer failed")
+
+// failingRenderer writes a partial line, then fails
+func failingRenderer(w io.Writer, canvas *Canvas) error {
+	io.WriteString(w, "partial")
+	return errTestRenderer
+}
+
+func TestRendererErrorsAreReturned(t *testing.T) {
+	a := New(WithRenderer(failingRenderer), WithSobel(false), WithOutputAspectRatio(1))
+
+	if _, err := a.ConvertContext(context.Background(), gradientImage(), 4, 2); !errors.Is(err, errTestRenderer) {
+		t.Errorf("ConvertContext() error = %v, want %v", err, errTestRenderer)
+	}
+	if err := a.ConvertTo(io.Discard, gradientImage(), 4, 2); !errors.Is(err, errTestRenderer) {
+		t.Errorf("ConvertTo() error = %v, want %v", err, errTestRenderer)
+	}
+
+	// Convert() cannot return the error, but must not panic either
+	if got := a.Convert(gradientImage(), 4, 2); got != "partial" {
+		t.Errorf("Convert() = %q, want the partial output", got)
+	}
+}
+
+func TestNilRendererFallsBackToANSI(t *testing.T) {
+	opts := ANSIRendererOptions{ResetAtLineEnd: true}
+	want := New(WithSobel(false), WithOutputAspectRatio(1), WithDefault4BitColorMapper(), WithRenderer(ANSIRenderer(opts))).Convert(gradientImage(), 4, 2)
+
+	a := New(WithSobel(false), WithOutputAspectRatio(1), WithDefault4BitColorMapper(), WithANSIRendererOptions(opts))
+	if a.Renderer != nil {
+		t.Fatal("Renderer is set by default")
+	}
+
+	if got := a.Convert(gradientImage(), 4, 2); got != want {
+		t.Errorf("Convert() = %q, want %q", got, want)
+	}
+
+	var buf strings.Builder
+	if err := a.ConvertTo(&buf, gradientImage(), 4, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("ConvertTo() = %q, want %q", got, want)
+	}
+}
+
+func TestConvertToMatchesANSIRenderer(t *testing.T) {
+	img := loadSampleImage(t, "3-mona_lisa.jpg")
+
+	// The left half of the Mona Lisa is transparent
+	halfTransparent := image.NewNRGBA(img.Bounds())
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if x < img.Bounds().Dx() / 2 {
+				c.A = 0
+			}
+			halfTransparent.SetNRGBA(x, y, c)
+		}
+	}
+
+	tests := []struct {
+		name	string
+		img		image.Image
+		opts	[]AsciiOption
+	}{
+		{"default", img, nil},
+		{"sobel with bold outlines", img, []AsciiOption{WithDefault8BitColorMapper(), WithSobel(true), WithBoldedSobelOutline(true)}},
+		{"no sobel", img, []AsciiOption{WithDefault4BitColorMapper(), WithSobel(false)}},
+		{"cell backgrounds", img, []AsciiOption{WithDefault24BitColorMapper(), WithCellBackgrounds(true)}},
+		{"transparent", halfTransparent, []AsciiOption{WithAlphaPolicy(AlphaPolicies.Transparent()), WithDefault24BitColorMapper(), WithCellBackgrounds(true)}},
+		{"reset at line end", img, []AsciiOption{WithDefault4BitColorMapper(), WithANSIRendererOptions(ANSIRendererOptions{ResetAtLineEnd: true})}},
+		{"parallel", img, []AsciiOption{WithDefault8BitColorMapper(), WithParallelism(4)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(tt.opts...)
+			want := renderToString(t, ANSIRenderer(a.ANSIRendererOptions), a.ConvertToGrid(tt.img, 60, 60))
+
+			var buf strings.Builder
+			if err := a.ConvertTo(&buf, tt.img, 60, 60); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != want {
+				t.Errorf("ConvertTo() differs from ANSIRenderer()\ngot  %q\nwant %q", got, want)
+			}
+		})
+	}
+}
+
+func TestConvertToDoesNotBuildTheCanvas(t *testing.T) {
+	img := loadSampleImage(t, "3-mona_lisa.jpg")
+	a := New(WithDefault8BitColorMapper())
+
+	allocated := func(convert func()) uint64 {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		convert()
+		runtime.ReadMemStats(&after)
+		return after.TotalAlloc - before.TotalAlloc
+	}
+
+	grid := allocated(func() { a.ConvertToGrid(img, 300, 300) })
+	streamed := allocated(func() { a.ConvertTo(io.Discard, img, 300, 300) })
+
+	// The cells of the canvas alone take width * height * the size of a cell
+	canvas := a.ConvertToGrid(img, 300, 300)
+	cells := uint64(canvas.Width * canvas.Height) * uint64(unsafe.Sizeof(Cell{}))
+	if streamed + cells / 2 > grid {
+		t.Errorf("ConvertTo() allocated %d bytes and ConvertToGrid() %d, want the %d bytes of cells saved", streamed, grid, cells)
+	}
+}
+
+func TestTextRenderer(t *testing.T) {
+	trailing := testCanvas(
+		[]Cell{{Rune: 'a'}, {Rune: ' '}, {Rune: ' '}},
+		[]Cell{{Rune: ' '}, {Rune: ' '}, {Rune: ' '}},
+		[]Cell{{Rune: ' '}, {Rune: 'b', Bold: true}, {Rune: ' ', Transparent: true}},
+	)
+
+	tests := []struct {
+		name	string
+		opts	TextRendererOptions
+		canvas	*Canvas
+		want	string
+	}{
+		{"colour and bold are dropped", TextRendererOptions{}, styledCanvas(), "abc\nd e\n"},
+		{"trailing space is kept", TextRendererOptions{}, trailing, "a  \n   \n b \n"},
+		{"trailing space is trimmed", TextRendererOptions{TrimTrailingSpace: true}, trailing, "a\n\n b\n"},
+		{"non ascii runes", TextRendererOptions{}, testCanvas([]Cell{{Rune: '█'}, {Rune: '░'}}), "█░\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderToString(t, TextRenderer(tt.opts), tt.canvas); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextRendererMatchesANSIWithoutEscapes(t *testing.T) {
+	a := New(WithSobel(true), WithDefault8BitColorMapper(), WithOutputAspectRatio(1))
+	canvas := a.ConvertToGrid(gradientImage(), 4, 2)
+
+	text := renderToString(t, TextRenderer(TextRendererOptions{}), canvas)
+	ansi := renderToString(t, ANSIRenderer(), canvas)
+
+	if strings.Contains(text, "\x1b") {
+		t.Errorf("text output has escape sequences: %q", text)
+	}
+	if stripped := stripANSI(ansi); stripped != text {
+		t.Errorf("text output = %q, want the ANSI output without escapes %q", text, stripped)
+	}
+}
+
+// stripANSI removes the SGR escape sequences from s
+func stripANSI(s string) string {
+	var sb strings.Builder
+	for len(s) > 0 {
+		if rest, ok := strings.CutPrefix(s, "\x1b["); ok {
+			end := strings.IndexByte(rest, 'm')
+			s = rest[end + 1:]
+			continue
+		}
+		sb.WriteByte(s[0])
+		s = s[1:]
+	}
+	return sb.String()
+}
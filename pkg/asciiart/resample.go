@@ -0,0 +1,62 @@
+package asciiart
+
+import (
+	"context"
+	"image"
+	"image/color"
+)
+
+/*
+resampleImageLinear resamples src onto a new newWidth x newHeight image by averaging every source pixel that falls inside each destination pixel (a box filter). Averaging is done in linear light, weighted by alpha, and the result is encoded back to sRGB. Averaging sRGB encoded values directly would make gradients and anti-aliased edges too dark.
+
+Returns ctx.Err() if ctx is cancelled before every pixel is resampled.
+*/
+func (a *AsciiConverter) resampleImageLinear(ctx context.Context, src image.Image, newWidth, newHeight int) (*image.NRGBA, error) {
+	srcBounds := src.Bounds()
+	srcWidth, srcHeight := srcBounds.Dx(), srcBounds.Dy()
+
+	resampledImg := image.NewNRGBA(image.Rect(0, 0, newWidth, newHeight))
+	read := nrgbaReader(src)
+
+	err := a.parallelRows(ctx, newHeight, func(y int) {
+		// Always cover at least one source row, in case we are upscaling
+		srcY0 := y * srcHeight / newHeight
+		srcY1 := max(srcY0 + 1, (y + 1) * srcHeight / newHeight)
+
+		for x := range newWidth {
+			srcX0 := x * srcWidth / newWidth
+			srcX1 := max(srcX0 + 1, (x + 1) * srcWidth / newWidth)
+
+			var rSum, gSum, bSum, aSum float64
+			for sy := srcY0; sy < srcY1; sy++ {
+				for sx := srcX0; sx < srcX1; sx++ {
+					c := read(srcBounds.Min.X + sx, srcBounds.Min.Y + sy)
+					alpha := float64(c.A) / 255
+
+					rSum += srgbDecodeTable[c.R] * alpha
+					gSum += srgbDecodeTable[c.G] * alpha
+					bSum += srgbDecodeTable[c.B] * alpha
+					aSum += alpha
+				}
+			}
+
+			count := float64((srcX1 - srcX0) * (srcY1 - srcY0))
+			if aSum == 0 {
+				// Fully transparent, there is no colour to average
+				continue
+			}
+
+			resampledImg.SetNRGBA(x, y, color.NRGBA{
+				R: linearToSRGB8(rSum / aSum),
+				G: linearToSRGB8(gSum / aSum),
+				B: linearToSRGB8(bSum / aSum),
+				A: uint8(clampLuminosity(aSum / count * 255)),
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resampledImg, nil
+}
@@ -0,0 +1,113 @@
+package asciiart
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestSRGBRoundTrip(t *testing.T) {
+	for i := range 256 {
+		if got := linearToSRGB8(srgbDecodeTable[i]); int(got) != i {
+			t.Errorf("linearToSRGB8(srgbDecodeTable[%d]) = %d", i, got)
+		}
+	}
+
+	if got := srgbToLinear(linearToSRGB(0.5)); math.Abs(got - 0.5) > 1e-9 {
+		t.Errorf("srgbToLinear(linearToSRGB(0.5)) = %v", got)
+	}
+}
+
+// checkerboard returns a width x height image alternating between a and b on every pixel
+func checkerboard(width, height int, a, b color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			if (x + y) % 2 == 0 {
+				img.Set(x, y, a)
+			} else {
+				img.Set(x, y, b)
+			}
+		}
+	}
+	return img
+}
+
+func TestResampleImageLinear(t *testing.T) {
+	black := color.NRGBA{A: 255}
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	transparentRed := color.NRGBA{R: 255}
+
+	tests := []struct {
+		name	string
+		src		image.Image
+		want	color.NRGBA
+	}{
+		// Half of the light of white is 188 once encoded, not 128
+		{"black and white average in linear light", checkerboard(4, 4, black, white), color.NRGBA{R: 188, G: 188, B: 188, A: 255}},
+		{"transparent pixels do not tint the colour", checkerboard(4, 4, transparentRed, white), color.NRGBA{R: 255, G: 255, B: 255, A: 128}},
+		{"fully transparent stays transparent", checkerboard(4, 4, transparentRed, transparentRed), color.NRGBA{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(WithLinearLight(true))
+			resampled, err := a.resampleImageLinear(context.Background(), tt.src, 2, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for y := range 2 {
+				for x := range 2 {
+					if got := resampled.NRGBAAt(x, y); got != tt.want {
+						t.Errorf("pixel (%d, %d) = %v, want %v", x, y, got, tt.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestResampleImageNonZeroOrigin(t *testing.T) {
+	black := color.NRGBA{A: 255}
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+
+	// Only the bottom right quarter is white, and the sub image starts there
+	src := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := range 8 {
+		for x := range 8 {
+			src.Set(x, y, black)
+			if x >= 4 && y >= 4 {
+				src.Set(x, y, white)
+			}
+		}
+	}
+	sub := src.SubImage(image.Rect(4, 4, 8, 8))
+
+	for _, linear := range []bool{false, true} {
+		a := New(WithLinearLight(linear))
+		resampled, err := a.resampleImage(context.Background(), sub, 2, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := color.NRGBAModel.Convert(resampled.At(0, 0)); got != white {
+			t.Errorf("linear %v: pixel (0, 0) = %v, want white", linear, got)
+		}
+	}
+}
+
+func TestLinearLightLuminosity(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+
+	// Rec. 709 weights red by 0.2126. In linear light that is encoded to 127, in sRGB directly it is 54
+	if got := New(WithLinearLight(true)).MapLuminosity(img).LuminosityAt(0, 0); got != 127 {
+		t.Errorf("linear light luminosity of red = %d, want 127", got)
+	}
+	if got := New().MapLuminosity(img).LuminosityAt(0, 0); got != 54 {
+		t.Errorf("sRGB luminosity of red = %d, want 54", got)
+	}
+}
@@ -0,0 +1,188 @@
+package asciiart
+
+import (
+	"bufio"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+/*
+sgrStyle is the set of SGR (Select Graphic Rendition) attributes the renderers write. The zero value is the default style of the terminal.
+*/
+type sgrStyle struct {
+	// FG is the escape sequence of the foreground colour (Cell.ColorEscape). Empty for the default colour
+	FG				string
+	// BG is the background colour (Cell.BG), written as true colour. Fully transparent for the default background
+	BG				color.RGBA
+	Bold			bool
+	Underline		bool
+}
+
+// cellStyle returns the style cell is drawn with
+func cellStyle(cell Cell) sgrStyle {
+	style := sgrStyle {
+		FG: cell.ColorEscape,
+		Bold: cell.Bold,
+	}
+	if cell.BG.A != 0 {
+		style.BG = cell.BG
+	}
+	return style
+}
+
+/*
+drawnStyle returns the style the terminal is in after cell is written in the style current. Transparent cells are written as a space, so only the attributes that show on a space (the background and underline) are removed for them.
+*/
+func drawnStyle(cell Cell, current sgrStyle) sgrStyle {
+	if cell.Transparent {
+		current.BG = color.RGBA{}
+		current.Underline = false
+		return current
+	}
+	return cellStyle(cell)
+}
+
+/*
+sgrWriter writes the escape sequences that change the style of the terminal, tracking the current style so that nothing is written unless an attribute changes. All attributes that change at once are combined into a single `\x1b[...;...m` sequence, and a full reset is used instead whenever it is shorter.
+
+The terminal is assumed to start in the default style.
+*/
+type sgrWriter struct {
+	w				*bufio.Writer
+	style			sgrStyle
+	// Scratch buffers for the sequences being compared, reused to avoid allocating per cell
+	incremental		[]byte
+	fromReset		[]byte
+}
+
+func newSGRWriter(w *bufio.Writer) *sgrWriter {
+	return &sgrWriter {
+		w: w,
+	}
+}
+
+// set changes the style of the terminal to next
+func (s *sgrWriter) set(next sgrStyle) {
+	params, raw := s.transition(s.style, next)
+	s.style = next
+
+	if len(params) > 0 {
+		s.w.WriteString("\x1b[")
+		s.w.Write(params)
+		s.w.WriteByte('m')
+	}
+	s.w.WriteString(raw)
+}
+
+// cost returns how many bytes set() writes to change the style of the terminal from prev to next
+func (s *sgrWriter) cost(prev, next sgrStyle) int {
+	params, raw := s.transition(prev, next)
+	if len(params) > 0 {
+		return len("\x1b[m") + len(params) + len(raw)
+	}
+	return len(raw)
+}
+
+/*
+transition returns the shortest SGR parameters that change the style from prev to next, and any escape sequences to write after them that could not be combined. params is only valid until the next call.
+*/
+func (s *sgrWriter) transition(prev, next sgrStyle) (params []byte, raw string) {
+	if next == prev {
+		return nil, ""
+	}
+
+	if next == (sgrStyle{}) {
+		return append(s.fromReset[:0], '0'), ""
+	}
+
+	// Escape sequences that are not a single SGR sequence (e.g. from a custom ANSIColorMapper) cannot be combined, so they are written after the combined sequence
+	var rawFG string
+
+	inc := s.incremental[:0]
+	if prev.Bold && !next.Bold {
+		inc = appendSGRParam(inc, "22")
+	}
+	if prev.Underline && !next.Underline {
+		inc = appendSGRParam(inc, "24")
+	}
+	if next.FG != prev.FG {
+		inc, rawFG = appendSGRColor(inc, next.FG, "39")
+	}
+	if next.BG != prev.BG {
+		inc = appendSGRBackground(inc, next.BG)
+	}
+	if next.Bold && !prev.Bold {
+		inc = appendSGRParam(inc, "1")
+	}
+	if next.Underline && !prev.Underline {
+		inc = appendSGRParam(inc, "4")
+	}
+	s.incremental = inc
+
+	// Resetting first and setting every attribute of next is sometimes shorter, e.g. when bold, underline and the colours are removed
+	var resetRawFG string
+	full := appendSGRParam(s.fromReset[:0], "0")
+	if next.FG != "" {
+		full, resetRawFG = appendSGRColor(full, next.FG, "39")
+	}
+	if next.BG.A != 0 {
+		full = appendSGRBackground(full, next.BG)
+	}
+	if next.Bold {
+		full = appendSGRParam(full, "1")
+	}
+	if next.Underline {
+		full = appendSGRParam(full, "4")
+	}
+	s.fromReset = full
+
+	if len(full) + len(resetRawFG) < len(inc) + len(rawFG) {
+		return full, resetRawFG
+	}
+	return inc, rawFG
+}
+
+// reset changes the terminal back to the default style, if it is not already
+func (s *sgrWriter) reset() {
+	s.set(sgrStyle{})
+}
+
+// appendSGRParam appends a parameter to a list of SGR parameters
+func appendSGRParam(params []byte, param string) []byte {
+	if len(params) > 0 {
+		params = append(params, ';')
+	}
+	return append(params, param...)
+}
+
+/*
+appendSGRColor appends the parameters of the colour escape sequence to params, or def if escape is empty. If escape is not a single SGR sequence, it is returned as raw to be written as is.
+*/
+func appendSGRColor(params []byte, escape string, def string) (_ []byte, raw string) {
+	if escape == "" {
+		return appendSGRParam(params, def), ""
+	}
+
+	inner, ok := strings.CutPrefix(escape, "\x1b[")
+	inner, hasSuffix := strings.CutSuffix(inner, "m")
+	if !ok || !hasSuffix || strings.ContainsAny(inner, "\x1bm") {
+		return params, escape
+	}
+
+	return appendSGRParam(params, inner), ""
+}
+
+// appendSGRBackground appends the parameters that set the true colour background bg, or the default background if bg is fully transparent
+func appendSGRBackground(params []byte, bg color.RGBA) []byte {
+	if bg.A == 0 {
+		return appendSGRParam(params, "49")
+	}
+
+	params = appendSGRParam(params, "48;2;")
+	params = strconv.AppendUint(params, uint64(bg.R), 10)
+	params = append(params, ';')
+	params = strconv.AppendUint(params, uint64(bg.G), 10)
+	params = append(params, ';')
+	return strconv.AppendUint(params, uint64(bg.B), 10)
+}
@@ -0,0 +1,190 @@
+package asciiart
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSGRWriter(t *testing.T) {
+	red, green := "\x1b[31m", "\x1b[32m"
+	bg, otherBG := color.RGBA{1, 2, 3, 255}, color.RGBA{4, 5, 6, 255}
+
+	tests := []struct {
+		name	string
+		from	sgrStyle
+		to		sgrStyle
+		want	string
+	}{
+		{"unchanged writes nothing", sgrStyle{FG: red, Bold: true}, sgrStyle{FG: red, Bold: true}, ""},
+		{"default writes nothing", sgrStyle{}, sgrStyle{}, ""},
+		{"colour from default", sgrStyle{}, sgrStyle{FG: red}, "\x1b[31m"},
+		{"colour change", sgrStyle{FG: red}, sgrStyle{FG: green}, "\x1b[32m"},
+		{"bold added", sgrStyle{FG: red}, sgrStyle{FG: red, Bold: true}, "\x1b[1m"},
+		{"bold removed", sgrStyle{FG: red, Bold: true}, sgrStyle{FG: red}, "\x1b[22m"},
+		{"colour and bold combined", sgrStyle{}, sgrStyle{FG: green, Bold: true}, "\x1b[32;1m"},
+		{"back to default is a reset", sgrStyle{FG: red, Bold: true}, sgrStyle{}, "\x1b[0m"},
+		// 0;32 is shorter than 22;32
+		{"reset when shorter", sgrStyle{FG: red, Bold: true}, sgrStyle{FG: green}, "\x1b[0;32m"},
+		// 0;1 is shorter than 39;1
+		{"colour removed", sgrStyle{FG: red}, sgrStyle{Bold: true}, "\x1b[0;1m"},
+		{"8 bit colour", sgrStyle{FG: red}, sgrStyle{FG: "\x1b[38;5;196m"}, "\x1b[38;5;196m"},
+		{"true colour", sgrStyle{}, sgrStyle{FG: "\x1b[38;2;1;2;3m", Bold: true}, "\x1b[38;2;1;2;3;1m"},
+		// Escapes that are not a single SGR sequence are written as is, after the combined sequence
+		{"raw escape", sgrStyle{}, sgrStyle{FG: "\x1b[1m\x1b[31m", Bold: true}, "\x1b[1m\x1b[1m\x1b[31m"},
+		{"raw escape without other changes", sgrStyle{FG: red}, sgrStyle{FG: "\x1b]custom"}, "\x1b]custom"},
+		{"background from default", sgrStyle{}, sgrStyle{BG: bg}, "\x1b[48;2;1;2;3m"},
+		{"background change", sgrStyle{FG: red, BG: bg}, sgrStyle{FG: red, BG: otherBG}, "\x1b[48;2;4;5;6m"},
+		{"background removed", sgrStyle{FG: red, BG: bg}, sgrStyle{FG: red}, "\x1b[49m"},
+		{"underline added", sgrStyle{FG: red}, sgrStyle{FG: red, Underline: true}, "\x1b[4m"},
+		{"underline removed", sgrStyle{FG: red, Underline: true}, sgrStyle{FG: red}, "\x1b[24m"},
+		{"every attribute combined", sgrStyle{}, sgrStyle{FG: green, BG: bg, Bold: true, Underline: true}, "\x1b[32;48;2;1;2;3;1;4m"},
+		// 0;48;2;1;2;3 is shorter than 22;24;39;48;2;1;2;3
+		{"reset when shorter with a background", sgrStyle{FG: red, Bold: true, Underline: true}, sgrStyle{BG: bg}, "\x1b[0;48;2;1;2;3m"},
+		// 0;4 is shorter than 39;49
+		{"reset when shorter with underline", sgrStyle{FG: red, BG: bg, Underline: true}, sgrStyle{Underline: true}, "\x1b[0;4m"},
+		{"raw escape with a background", sgrStyle{}, sgrStyle{FG: "\x1b]custom", BG: bg}, "\x1b[48;2;1;2;3m\x1b]custom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			bw := bufio.NewWriter(&buf)
+
+			sgr := newSGRWriter(bw)
+			if got := sgr.cost(tt.from, tt.to); got != len(tt.want) {
+				t.Errorf("cost() = %d, want %d", got, len(tt.want))
+			}
+
+			sgr.style = tt.from
+			sgr.set(tt.to)
+			bw.Flush()
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if sgr.style != tt.to {
+				t.Errorf("style = %+v after set(), want %+v", sgr.style, tt.to)
+			}
+		})
+	}
+}
+
+func TestSGRWriterReset(t *testing.T) {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	sgr := newSGRWriter(bw)
+
+	// The terminal starts in the default style, so there is nothing to reset
+	sgr.reset()
+	sgr.set(sgrStyle{FG: "\x1b[31m", Bold: true})
+	sgr.reset()
+	sgr.reset()
+	bw.Flush()
+
+	if got, want := buf.String(), "\x1b[31;1m\x1b[0m"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// loadSampleImage decodes one of the sample images the CLI is documented with
+func loadSampleImage(tb testing.TB, name string) image.Image {
+	tb.Helper()
+
+	f, err := os.Open(filepath.Join("..", "..", "asciiart_cmd_images", name))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return img
+}
+
+// naiveANSIRenderer writes the colour and boldness of every cell before it, the way output was written before the SGR writer
+func naiveANSIRenderer(w io.Writer, canvas *Canvas) error {
+	bw := bufio.NewWriter(w)
+	for _, row := range canvas.Cells {
+		for _, cell := range row {
+			if cell.Transparent {
+				bw.WriteRune(' ')
+				continue
+			}
+
+			bw.WriteString("\x1b[0m")
+			bw.WriteString(cell.ColorEscape)
+			if cell.Bold {
+				bw.WriteString("\x1b[1m")
+			}
+			bw.WriteRune(cell.Rune)
+		}
+		bw.WriteRune('\n')
+	}
+	bw.WriteString("\x1b[0m")
+	return bw.Flush()
+}
+
+// sampleCanvas converts the Mona Lisa in 8 bit colour with bold outlines, which changes style often
+func sampleCanvas(tb testing.TB) *Canvas {
+	tb.Helper()
+
+	a := New(WithDefault8BitColorMapper(), WithSobel(true), WithBoldedSobelOutline(true))
+	return a.ConvertToGrid(loadSampleImage(tb, "3-mona_lisa.jpg"), 150, 150)
+}
+
+func TestANSIRendererIsSmallerThanPerCellEscapes(t *testing.T) {
+	canvas := sampleCanvas(t)
+
+	ansi := renderToString(t, ANSIRenderer(), canvas)
+	naive := renderToString(t, naiveANSIRenderer, canvas)
+
+	// Both must draw the same characters
+	if stripANSI(ansi) != stripANSI(naive) {
+		t.Fatal("ANSI output draws different characters to the naive output")
+	}
+	if len(ansi) >= len(naive) {
+		t.Errorf("ANSI output is %d bytes, want less than the %d bytes of per cell escapes", len(ansi), len(naive))
+	}
+	t.Logf("ANSI output is %d bytes, per cell escapes %d bytes (%.1f%%)", len(ansi), len(naive), 100 * float64(len(ansi)) / float64(len(naive)))
+
+	if n := strings.Count(ansi, "\n"); n != canvas.Height {
+		t.Errorf("ANSI output has %d lines, want %d", n, canvas.Height)
+	}
+}
+
+func BenchmarkANSIRenderer(b *testing.B) {
+	canvas := sampleCanvas(b)
+
+	renderers := []struct {
+		name		string
+		renderer	Renderer
+	}{
+		{"sgr", ANSIRenderer()},
+		{"sgr reset at line end", ANSIRenderer(ANSIRendererOptions{ResetAtLineEnd: true})},
+		{"per cell escapes", naiveANSIRenderer},
+	}
+
+	for _, r := range renderers {
+		b.Run(r.name, func(b *testing.B) {
+			var buf bytes.Buffer
+			for b.Loop() {
+				buf.Reset()
+				if err := r.renderer(&buf, canvas); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(buf.Len()), "output-bytes")
+		})
+	}
+}
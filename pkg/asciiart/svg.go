@@ -0,0 +1,176 @@
+package asciiart
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+const (
+	defaultSVGCellWidth		= 8
+	defaultSVGCellHeight	= 16
+	defaultSVGFontFamily	= "monospace"
+	// svgBaselineRatio is the position of the text baseline within a cell, as a fraction of the cell height
+	svgBaselineRatio		= 0.8
+)
+
+/*
+SVGRendererOptions represents the configuration of the SVG renderer. The zero value uses 8x16 cells in the default monospace font.
+*/
+type SVGRendererOptions struct {
+	// CellWidth is the width of each character in SVG user units. Defaults to 8
+	CellWidth		float64
+	// CellHeight is the height of each character (the line height) in SVG user units. Defaults to 16
+	CellHeight		float64
+	// FontFamily is the CSS font family of the text. Defaults to "monospace"
+	FontFamily		string
+	// FontSize is the font size in SVG user units. Defaults to CellHeight
+	FontSize		float64
+	// Foreground is the colour of characters without colour (see Cell.HasColor()). nil uses the SVG default (black)
+	Foreground		color.Color
+	// Background fills the whole image. nil leaves the image transparent
+	Background		color.Color
+	// DrawCellBackgrounds draws a rectangle behind every run of cells with a background colour (see Cell.BG). The converter only fills in Cell.BG with WithCellBackgrounds()
+	DrawCellBackgrounds bool
+}
+
+// svgNum formats a coordinate without trailing zeros
+func svgNum(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func svgColor(c color.Color) string {
+	return "#" + hexColor(color.RGBAModel.Convert(c).(color.RGBA))
+}
+
+// svgRun is the styling of a run of cells drawn by a single <tspan>
+type svgRun struct {
+	fg			color.RGBA
+	hasFG		bool
+	bold		bool
+}
+
+func cellSVGRun(cell Cell) svgRun {
+	run := svgRun{bold: cell.Bold}
+	if cell.HasColor() {
+		run.fg = cellDisplayColor(cell)
+		run.hasFG = true
+	}
+	return run
+}
+
+/*
+writeSVGBackgrounds writes a <rect> for every run of adjacent cells in row y with the same background colour.
+*/
+func writeSVGBackgrounds(bw *bufio.Writer, row []Cell, y int, cw, ch float64) {
+	for x := 0; x < len(row); {
+		bg := row[x].BG
+		if bg.A == 0 || row[x].Transparent {
+			x++
+			continue
+		}
+
+		end := x + 1
+		for end < len(row) && row[end].BG == bg && !row[end].Transparent {
+			end++
+		}
+
+		fmt.Fprintf(bw, "<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" fill=\"#%s\"/>\n",
+			svgNum(float64(x) * cw), svgNum(float64(y) * ch), svgNum(float64(end - x) * cw), svgNum(ch), hexColor(bg))
+
+		x = end
+	}
+}
+
+/*
+SVGRenderer returns a Renderer that writes the canvas as an SVG image. Every row is a <text> element, and adjacent characters with the same colour and boldness are merged into a single <tspan>. Each <tspan> is positioned on the cell grid and stretched to the width of its cells, so the columns line up regardless of the font.
+
+Colours are the palette colours picked by the color mapper, like HTMLRenderer(), so adjacent characters mapped to the same code share a <tspan>.
+*/
+func SVGRenderer(opts SVGRendererOptions) Renderer {
+	cw, ch := opts.CellWidth, opts.CellHeight
+	if cw <= 0 {
+		cw = defaultSVGCellWidth
+	}
+	if ch <= 0 {
+		ch = defaultSVGCellHeight
+	}
+
+	fontFamily := opts.FontFamily
+	if fontFamily == "" {
+		fontFamily = defaultSVGFontFamily
+	}
+
+	fontSize := opts.FontSize
+	if fontSize <= 0 {
+		fontSize = ch
+	}
+
+	return func(w io.Writer, canvas *Canvas) error {
+		bw := bufio.NewWriter(w)
+
+		width, height := float64(canvas.Width) * cw, float64(canvas.Height) * ch
+
+		fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%s\" height=\"%s\" viewBox=\"0 0 %s %s\">\n",
+			svgNum(width), svgNum(height), svgNum(width), svgNum(height))
+
+		if opts.Background != nil {
+			fmt.Fprintf(bw, "<rect width=\"100%%\" height=\"100%%\" fill=\"%s\"/>\n", svgColor(opts.Background))
+		}
+
+		if opts.DrawCellBackgrounds {
+			for y, row := range canvas.Cells {
+				writeSVGBackgrounds(bw, row, y, cw, ch)
+			}
+		}
+
+		fmt.Fprintf(bw, "<g font-family=\"%s\" font-size=\"%s\" xml:space=\"preserve\"", html.EscapeString(fontFamily), svgNum(fontSize))
+		if opts.Foreground != nil {
+			fmt.Fprintf(bw, " fill=\"%s\"", svgColor(opts.Foreground))
+		}
+		bw.WriteString(">\n")
+
+		for y, row := range canvas.Cells {
+			fmt.Fprintf(bw, "<text y=\"%s\">", svgNum((float64(y) + svgBaselineRatio) * ch))
+
+			for x := 0; x < len(row); {
+				if row[x].Transparent {
+					x++
+					continue
+				}
+
+				run := cellSVGRun(row[x])
+				end := x + 1
+				for end < len(row) && !row[end].Transparent && cellSVGRun(row[end]) == run {
+					end++
+				}
+
+				fmt.Fprintf(bw, "<tspan x=\"%s\" textLength=\"%s\" lengthAdjust=\"spacing\"",
+					svgNum(float64(x) * cw), svgNum(float64(end - x) * cw))
+				if run.hasFG {
+					fmt.Fprintf(bw, " fill=\"#%s\"", hexColor(run.fg))
+				}
+				if run.bold {
+					bw.WriteString(" font-weight=\"bold\"")
+				}
+				bw.WriteString(">")
+
+				for _, cell := range row[x:end] {
+					writeMarkupRune(bw, cell.Rune)
+				}
+				bw.WriteString("</tspan>")
+
+				x = end
+			}
+
+			bw.WriteString("</text>\n")
+		}
+
+		bw.WriteString("</g>\n</svg>\n")
+
+		return bw.Flush()
+	}
+}
@@ -0,0 +1,110 @@
+package asciiart
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestSVGRenderer(t *testing.T) {
+	blue := color.RGBA{0, 0, 255, 255}
+	backgrounds := styledCanvas()
+	backgrounds.Cells[0][0].BG = blue
+	backgrounds.Cells[0][1].BG = blue
+	// Transparent cells are never drawn, even with a background
+	backgrounds.Cells[1][1].BG = blue
+	backgrounds.Cells[1][2].BG = color.RGBA{1, 2, 3, 255}
+
+	text := func(y, y2 string) string {
+		return "<text y=\"" + y + "\"><tspan x=\"0\" textLength=\"16\" lengthAdjust=\"spacing\" fill=\"#cd0000\">ab</tspan>" +
+			"<tspan x=\"16\" textLength=\"8\" lengthAdjust=\"spacing\" fill=\"#00cd00\" font-weight=\"bold\">c</tspan></text>\n" +
+			"<text y=\"" + y2 + "\"><tspan x=\"0\" textLength=\"8\" lengthAdjust=\"spacing\">d</tspan>" +
+			"<tspan x=\"16\" textLength=\"8\" lengthAdjust=\"spacing\" fill=\"#00cd00\">e</tspan></text>\n"
+	}
+
+	tests := []struct {
+		name	string
+		opts	SVGRendererOptions
+		canvas	*Canvas
+		want	string
+	}{
+		{
+			name: "defaults",
+			canvas: styledCanvas(),
+			want: "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"24\" height=\"32\" viewBox=\"0 0 24 32\">\n" +
+				"<g font-family=\"monospace\" font-size=\"16\" xml:space=\"preserve\">\n" +
+				text("12.8", "28.8") +
+				"</g>\n</svg>\n",
+		},
+		{
+			name: "backgrounds and colours",
+			opts: SVGRendererOptions{FontFamily: "A&B", Foreground: color.White, Background: color.Black, DrawCellBackgrounds: true},
+			canvas: backgrounds,
+			want: "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"24\" height=\"32\" viewBox=\"0 0 24 32\">\n" +
+				"<rect width=\"100%\" height=\"100%\" fill=\"#000000\"/>\n" +
+				"<rect x=\"0\" y=\"0\" width=\"16\" height=\"16\" fill=\"#0000ff\"/>\n" +
+				"<rect x=\"16\" y=\"16\" width=\"8\" height=\"16\" fill=\"#010203\"/>\n" +
+				"<g font-family=\"A&amp;B\" font-size=\"16\" xml:space=\"preserve\" fill=\"#ffffff\">\n" +
+				text("12.8", "28.8") +
+				"</g>\n</svg>\n",
+		},
+		{
+			name: "backgrounds are off by default",
+			canvas: backgrounds,
+			want: "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"24\" height=\"32\" viewBox=\"0 0 24 32\">\n" +
+				"<g font-family=\"monospace\" font-size=\"16\" xml:space=\"preserve\">\n" +
+				text("12.8", "28.8") +
+				"</g>\n</svg>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderToString(t, SVGRenderer(tt.opts), tt.canvas); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSVGRendererCellSize(t *testing.T) {
+	got := renderToString(t, SVGRenderer(SVGRendererOptions{CellWidth: 10, CellHeight: 20, FontSize: 18}), testCanvas([]Cell{{Rune: '<'}, {Rune: 'b'}}))
+	want := "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"20\" height=\"20\" viewBox=\"0 0 20 20\">\n" +
+		"<g font-family=\"monospace\" font-size=\"18\" xml:space=\"preserve\">\n" +
+		"<text y=\"16\"><tspan x=\"0\" textLength=\"20\" lengthAdjust=\"spacing\">&lt;b</tspan></text>\n" +
+		"</g>\n</svg>\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCellBackgrounds(t *testing.T) {
+	img := gradientImage()
+
+	canvas := New(WithSobel(false), WithOutputAspectRatio(1)).ConvertToGrid(img, 4, 2)
+	for y, row := range canvas.Cells {
+		for x, cell := range row {
+			if cell.BG != (color.RGBA{}) {
+				t.Errorf("cell (%d, %d) has background %v without CellBackgrounds", x, y, cell.BG)
+			}
+		}
+	}
+
+	for _, sobel := range []bool{false, true} {
+		canvas = New(WithSobel(sobel), WithOutputAspectRatio(1), WithCellBackgrounds(true)).ConvertToGrid(img, 4, 2)
+		for y, row := range canvas.Cells {
+			for x, cell := range row {
+				want := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				if cell.BG != want {
+					t.Errorf("sobel %v: cell (%d, %d) background = %v, want %v", sobel, x, y, cell.BG, want)
+				}
+			}
+		}
+	}
+
+	// The backgrounds reach the SVG
+	a := New(WithSobel(false), WithOutputAspectRatio(1), WithCellBackgrounds(true), WithRenderer(SVGRenderer(SVGRendererOptions{DrawCellBackgrounds: true})))
+	if out := a.Convert(img, 4, 2); !strings.Contains(out, "fill=\"#ffffff\"/>") {
+		t.Errorf("SVG has no white cell background:\n%s", out)
+	}
+}
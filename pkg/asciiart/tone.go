@@ -0,0 +1,67 @@
+package asciiart
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+/*
+toneCurve returns a lookup table mapping an 8 bit channel (or luminosity) onto its value after the Gamma, Contrast and Brightness adjustments have been applied (in that order). The boolean is false if the adjustments have no effect, so the stage can be skipped entirely.
+*/
+func (a *AsciiConverter) toneCurve() (*[256]uint8, bool) {
+	gamma := a.Gamma
+	if gamma <= 0 {
+		gamma = 1
+	}
+
+	if gamma == 1 && a.Contrast == 0 && a.Brightness == 0 {
+		return nil, false
+	}
+
+	// Map contrast [-1, 1] onto a slope. 0 keeps the slope at 1, -1 flattens everything to mid grey, and 1 would be infinitely steep
+	contrast := min(0.99, max(-1, a.Contrast))
+	slope := (1 + contrast) / (1 - contrast)
+
+	var curve [256]uint8
+	for i := range curve {
+		v := math.Pow(float64(i) / 255, 1 / gamma)
+		v = (v - 0.5) * slope + 0.5
+		v += a.Brightness
+
+		curve[i] = uint8(clampLuminosity(v * 255))
+	}
+
+	return &curve, true
+}
+
+/*
+adjustTone applies the tone curve (see toneCurve()) to every channel of img. The luminosity is computed from the adjusted image, so the character ramp and the color mappers see the same correction. img is returned as is if there is nothing to adjust.
+
+Returns ctx.Err() if ctx is cancelled before every pixel is adjusted.
+*/
+func (a *AsciiConverter) adjustTone(ctx context.Context, img image.Image) (image.Image, error) {
+	curve, ok := a.toneCurve()
+	if !ok {
+		return img, nil
+	}
+
+	bounds := img.Bounds()
+	adjusted := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	read := nrgbaReader(img)
+
+	err := a.parallelRows(ctx, bounds.Dy(), func(y int) {
+		for x := range bounds.Dx() {
+			// Adjust the straight (non alpha premultiplied) colour, so transparency is not affected
+			c := read(bounds.Min.X + x, bounds.Min.Y + y)
+			c.R, c.G, c.B = curve[c.R], curve[c.G], curve[c.B]
+
+			adjusted.SetNRGBA(x, y, c)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return adjusted, nil
+}
@@ -0,0 +1,86 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestToneCurve(t *testing.T) {
+	tests := []struct {
+		name		string
+		opts		[]AsciiOption
+		// want maps an input channel value onto its expected output
+		want		map[int]int
+	}{
+		{"brightness up", []AsciiOption{WithBrightness(0.2)}, map[int]int{0: 51, 100: 151, 250: 255}},
+		{"brightness down", []AsciiOption{WithBrightness(-0.2)}, map[int]int{0: 0, 100: 49, 255: 204}},
+		{"contrast flattens to mid grey", []AsciiOption{WithContrast(-1)}, map[int]int{0: 128, 64: 128, 255: 128}},
+		{"contrast up keeps mid grey", []AsciiOption{WithContrast(0.5)}, map[int]int{0: 0, 64: 0, 128: 129, 191: 255}},
+		{"gamma brightens midtones", []AsciiOption{WithGamma(2)}, map[int]int{0: 0, 64: 128, 255: 255}},
+		{"gamma darkens midtones", []AsciiOption{WithGamma(0.5)}, map[int]int{0: 0, 128: 64, 255: 255}},
+		// Gamma first (64 -> 128), then brightness (128 -> 179)
+		{"gamma before brightness", []AsciiOption{WithBrightness(0.2), WithGamma(2)}, map[int]int{64: 179}},
+		// Contrast first (128 -> 129), then brightness (129 -> 78). The other way round would give 0
+		{"contrast before brightness", []AsciiOption{WithBrightness(-0.2), WithContrast(0.5)}, map[int]int{128: 78}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curve, ok := New(tt.opts...).toneCurve()
+			if !ok {
+				t.Fatal("toneCurve() reported nothing to adjust")
+			}
+
+			for in, want := range tt.want {
+				if got := int(curve[in]); got != want {
+					t.Errorf("curve[%d] = %d, want %d", in, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestToneCurveIdentity(t *testing.T) {
+	for _, opts := range [][]AsciiOption{
+		nil,
+		{WithGamma(1), WithContrast(0), WithBrightness(0)},
+		{WithGamma(-3)},
+	} {
+		if _, ok := New(opts...).toneCurve(); ok {
+			t.Errorf("toneCurve() with %d options reported an adjustment, want none", len(opts))
+		}
+	}
+}
+
+func TestAdjustToneKeepsAlpha(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(10, 20, 12, 21))
+	img.SetNRGBA(10, 20, color.NRGBA{R: 0, G: 100, B: 200, A: 80})
+	img.SetNRGBA(11, 20, color.NRGBA{R: 50, G: 50, B: 50, A: 255})
+
+	a := New(WithBrightness(0.2))
+	adjusted := a.MapLuminosity(img)
+
+	// Adjusted images always start at 0, 0
+	want := []color.NRGBA{
+		{R: 51, G: 151, B: 251, A: 80},
+		{R: 101, G: 101, B: 101, A: 255},
+	}
+	for x, w := range want {
+		if got := color.NRGBAModel.Convert(adjusted.At(x, 0)).(color.NRGBA); got != w {
+			t.Errorf("pixel %d = %v, want %v", x, got, w)
+		}
+	}
+}
+
+func TestToneAdjustsLuminosity(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+	img.SetGray(0, 0, color.Gray{Y: 64})
+
+	if got := New().MapLuminosity(img).LuminosityAt(0, 0); got != 64 {
+		t.Errorf("luminosity without adjustments = %d, want 64", got)
+	}
+	if got := New(WithGamma(2)).MapLuminosity(img).LuminosityAt(0, 0); got != 128 {
+		t.Errorf("luminosity with gamma 2 = %d, want 128", got)
+	}
+}